@@ -1,29 +1,37 @@
 package chainload
 
 import (
+	"bytes"
 	"context"
 	"math/big"
 	"math/rand"
+	"sort"
 	"sync"
+	"sync/atomic"
 
 	"github.com/gochain/gochain/v3/accounts"
 	"github.com/gochain/gochain/v3/accounts/keystore"
 	"github.com/gochain/gochain/v3/common"
 	"github.com/gochain/gochain/v3/core/types"
+	"go.uber.org/zap"
 )
 
 type AccountStore struct {
-	ks      *keystore.KeyStore
-	chainID *big.Int
-	ksAccts []accounts.Account
-	pass    string
+	ks        *keystore.KeyStore
+	chainID   *big.Int
+	ksAccts   []accounts.Account
+	pass      string
+	statePath string // Optional file to persist pools and seeds across runs.
 
 	acctsMu    sync.RWMutex
 	nextIdx    int
 	ksAcctsSet map[common.Address]struct{}
 	addrs      []common.Address
 	pools      map[int]map[common.Address]acctNonce
-	seeds      map[common.Address]struct{}
+	seeds      map[common.Address]uint64 // Last known nonce of each seeder.
+	unlocked   sync.Map                  // common.Address -> struct{}
+	prevSeeds  []acctNonce               // Seeders from the previous run, to be reused first.
+	balances   map[common.Address]*big.Int
 }
 
 type acctNonce struct {
@@ -31,28 +39,46 @@ type acctNonce struct {
 	nonce uint64
 }
 
-func NewAccountStore(ks *keystore.KeyStore, chainID *big.Int, pass string) *AccountStore {
-	return &AccountStore{
+// NewAccountStore returns a new AccountStore for ks. If statePath is set, then
+// state persisted by a previous run is loaded from it.
+func NewAccountStore(ks *keystore.KeyStore, chainID *big.Int, pass, statePath string) (*AccountStore, error) {
+	a := &AccountStore{
 		ks:         ks,
 		ksAccts:    ks.Accounts(),
 		chainID:    chainID,
 		pass:       pass,
+		statePath:  statePath,
 		ksAcctsSet: make(map[common.Address]struct{}),
 		pools:      make(map[int]map[common.Address]acctNonce),
-		seeds:      make(map[common.Address]struct{}),
+		seeds:      make(map[common.Address]uint64),
+		balances:   make(map[common.Address]*big.Int),
 	}
+	if statePath != "" {
+		if err := a.load(); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
 }
 
 func (a *AccountStore) SignTx(acct accounts.Account, tx *types.Transaction) (*types.Transaction, error) {
 	return a.ks.SignTx(acct, tx, a.chainID)
 }
 
-func (a *AccountStore) NextRecv(send common.Address, n int) []common.Address {
+// signTxChainID signs tx for a specific chain, which may not be the store's.
+func (a *AccountStore) signTxChainID(acct accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return a.ks.SignTx(acct, tx, chainID)
+}
+
+func (a *AccountStore) NextRecv(rng *rand.Rand, send common.Address, n int) []common.Address {
 	a.acctsMu.RLock()
 	defer a.acctsMu.RUnlock()
 
+	if len(a.addrs) == 0 {
+		return nil
+	}
 	var addrs []common.Address
-	start := rand.Intn(len(a.addrs))
+	start := rng.Intn(len(a.addrs))
 	for i := 0; len(addrs) < n && i < len(a.addrs); i++ {
 		addr := a.addrs[(start+i)%len(a.addrs)]
 		if addr != send {
@@ -62,16 +88,23 @@ func (a *AccountStore) NextRecv(send common.Address, n int) []common.Address {
 	return addrs
 }
 
-func (a *AccountStore) Next(ctx context.Context, node int) (acct *accounts.Account, nonce uint64, err error) {
+func (a *AccountStore) Next(ctx context.Context, rng *rand.Rand, node int) (acct *accounts.Account, nonce uint64, err error) {
 	a.acctsMu.Lock()
 	defer a.acctsMu.Unlock()
-	if len(a.pools) > 0 && rand.Intn(2) == 0 {
+	if len(a.pools) > 0 && rng.Intn(2) == 0 {
 		pool := a.pools[node]
-		if pool != nil {
-			for addr, an := range pool {
-				delete(pool, addr)
-				return an.Account, an.nonce, nil
+		if len(pool) > 0 {
+			// Take the lowest address, rather than depending on map order.
+			var next *common.Address
+			for addr := range pool {
+				if next == nil || bytes.Compare(addr[:], next[:]) < 0 {
+					addr := addr
+					next = &addr
+				}
 			}
+			an := pool[*next]
+			delete(pool, *next)
+			return an.Account, an.nonce, a.unlock(*an.Account)
 		}
 	}
 
@@ -79,10 +112,37 @@ func (a *AccountStore) Next(ctx context.Context, node int) (acct *accounts.Accou
 	if acct == nil {
 		return
 	}
-	err = a.ks.Unlock(*acct, a.pass)
+	err = a.unlock(*acct)
 	return
 }
 
+// NextAny returns the pooled account with the lowest address from any node, or
+// else the next keystore account, along with its last known nonce (or 0).
+func (a *AccountStore) NextAny() (*accounts.Account, uint64, error) {
+	a.acctsMu.Lock()
+	defer a.acctsMu.Unlock()
+	var next *common.Address
+	var nextNode int
+	for node, pool := range a.pools {
+		for addr := range pool {
+			if next == nil || bytes.Compare(addr[:], next[:]) < 0 {
+				addr := addr
+				next, nextNode = &addr, node
+			}
+		}
+	}
+	if next != nil {
+		an := a.pools[nextNode][*next]
+		delete(a.pools[nextNode], *next)
+		return an.Account, an.nonce, a.unlock(*an.Account)
+	}
+	acct := a.nextAcct()
+	if acct == nil {
+		return nil, 0, nil
+	}
+	return acct, 0, a.unlock(*acct)
+}
+
 func (a *AccountStore) New(ctx context.Context) (*accounts.Account, error) {
 	acct, err := a.ks.NewAccount(a.pass)
 	if err != nil {
@@ -92,7 +152,7 @@ func (a *AccountStore) New(ctx context.Context) (*accounts.Account, error) {
 	a.acctsMu.Lock()
 	a.addrs = append(a.addrs, acct.Address)
 	a.acctsMu.Unlock()
-	return &acct, a.ks.Unlock(acct, a.pass)
+	return &acct, a.unlock(acct)
 }
 
 func (a *AccountStore) Return(acct *accounts.Account, node int, nonce uint64) {
@@ -106,27 +166,149 @@ func (a *AccountStore) Return(acct *accounts.Account, node int, nonce uint64) {
 	a.acctsMu.Unlock()
 }
 
-func (a *AccountStore) RandSeed() *common.Address {
+// ReturnSeed records the last known nonce of a seeder account.
+func (a *AccountStore) ReturnSeed(acct *accounts.Account, nonce uint64) {
+	a.acctsMu.Lock()
+	a.seeds[acct.Address] = nonce
+	a.acctsMu.Unlock()
+}
+
+// noteBalance records the last known balance of an account.
+func (a *AccountStore) noteBalance(addr common.Address, bal *big.Int) {
+	if bal == nil {
+		return
+	}
+	a.acctsMu.Lock()
+	a.balances[addr] = new(big.Int).Set(bal)
+	a.acctsMu.Unlock()
+}
+
+// lastBalance returns the last known balance of addr, or nil.
+func (a *AccountStore) lastBalance(addr common.Address) *big.Int {
+	a.acctsMu.RLock()
+	defer a.acctsMu.RUnlock()
+	if b := a.balances[addr]; b != nil {
+		return new(big.Int).Set(b)
+	}
+	return nil
+}
+
+func (a *AccountStore) RandSeed(rng *rand.Rand) *common.Address {
 	a.acctsMu.RLock()
 	defer a.acctsMu.RUnlock()
+	if len(a.seeds) == 0 {
+		return nil
+	}
+	addrs := make([]common.Address, 0, len(a.seeds))
 	for addr := range a.seeds {
-		return &addr
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	return &addrs[rng.Intn(len(addrs))]
+}
+
+// unlock unlocks acct, unless it has already been unlocked.
+func (a *AccountStore) unlock(acct accounts.Account) error {
+	if _, ok := a.unlocked.Load(acct.Address); ok {
+		return nil
+	}
+	if err := a.ks.Unlock(acct, a.pass); err != nil {
+		return err
 	}
+	a.unlocked.Store(acct.Address, struct{}{})
 	return nil
 }
 
-// NextSeed returns the next available account from the keystore.
-func (a *AccountStore) NextSeed() (*accounts.Account, error) {
+// Unlock unlocks up to n of the accounts to be used next, with the given number
+// of workers: seeders from the previous run, pooled accounts, and then new
+// keystore accounts. Encrypted keys are slow to decrypt, so this is much faster
+// than unlocking them one at a time as they are assigned. Accounts which fail to
+// unlock are logged and skipped, and are tried again if assigned. It returns the
+// number unlocked, which is short if ctx is done first.
+func (a *AccountStore) Unlock(ctx context.Context, lgr *zap.Logger, workers, n int) int {
+	if workers < 1 {
+		workers = 1
+	}
+	accts := make(chan accounts.Account)
+	var unlocked int64
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for acct := range accts {
+				if err := a.unlock(acct); err != nil {
+					lgr.Warn("Failed to unlock account", zap.Stringer("account", acct.Address), zap.Error(err))
+					continue
+				}
+				atomic.AddInt64(&unlocked, 1)
+			}
+		}()
+	}
+loop:
+	for _, acct := range a.upcoming(n) {
+		select {
+		case <-ctx.Done():
+			break loop
+		case accts <- acct:
+		}
+	}
+	close(accts)
+	wg.Wait()
+	return int(unlocked)
+}
+
+// upcoming returns up to n of the accounts to be used next.
+func (a *AccountStore) upcoming(n int) []accounts.Account {
+	a.acctsMu.RLock()
+	defer a.acctsMu.RUnlock()
+	var accts []accounts.Account
+	for _, an := range a.prevSeeds {
+		accts = append(accts, *an.Account)
+	}
+	for _, pool := range a.pools {
+		for _, an := range pool {
+			accts = append(accts, *an.Account)
+		}
+	}
+	for i := a.nextIdx; i < len(a.ksAccts) && len(accts) < n; i++ {
+		acct := a.ksAccts[i]
+		if acct == (accounts.Account{}) {
+			continue
+		}
+		if _, ok := a.ksAcctsSet[acct.Address]; ok {
+			continue
+		}
+		accts = append(accts, acct)
+	}
+	if len(accts) > n {
+		accts = accts[:n]
+	}
+	return accts
+}
+
+// NextSeed returns the next available account from the keystore, preferring
+// seeders from the previous run, along with its last known nonce (or 0).
+func (a *AccountStore) NextSeed() (*accounts.Account, uint64, error) {
 	a.acctsMu.Lock()
-	acct := a.nextAcct()
+	var acct *accounts.Account
+	var nonce uint64
+	if len(a.prevSeeds) > 0 {
+		acct, nonce = a.prevSeeds[0].Account, a.prevSeeds[0].nonce
+		a.prevSeeds = a.prevSeeds[1:]
+	} else {
+		acct = a.nextAcct()
+	}
 	if acct != nil {
-		a.seeds[acct.Address] = struct{}{}
+		a.seeds[acct.Address] = nonce
 	}
 	a.acctsMu.Unlock()
 	if acct == nil {
-		return nil, nil
+		return nil, 0, nil
 	}
-	return acct, a.ks.Unlock(*acct, a.pass)
+	return acct, nonce, a.unlock(*acct)
 }
 
 // nextAcct returns the next available account from the keystore, or nil if none
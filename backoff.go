@@ -12,6 +12,7 @@ import (
 type backOff struct {
 	wait, maxWait time.Duration
 	lgr           *zap.Logger
+	rng           *rand.Rand // Jitter.
 }
 
 func (b *backOff) do(ctx context.Context, fn func() error) bool {
@@ -26,7 +27,7 @@ func (b *backOff) doTimed(ctx context.Context, timer metrics.Timer, fn func() er
 		if ctx.Err() != nil {
 			return false
 		}
-		if wait = randBetweenDur(3/2*wait, 5/2*wait); wait > b.maxWait {
+		if wait = randBetweenDur(b.rng, 3/2*wait, 5/2*wait); wait > b.maxWait {
 			wait = b.maxWait
 		}
 		b.lgr.Warn("Operation failed - pausing", zap.Duration("wait", wait), zap.Int("attempt", errs), zap.Error(err))
@@ -42,10 +43,15 @@ func (b *backOff) doTimed(ctx context.Context, timer metrics.Timer, fn func() er
 	return true
 }
 
-func randBetweenDur(start, end time.Duration) time.Duration {
-	return (start + time.Duration(rand.Int63n(int64(end-start)))).Round(time.Second)
+// newRand returns a new rng, seeded from rng.
+func newRand(rng *rand.Rand) *rand.Rand {
+	return rand.New(rand.NewSource(rng.Int63()))
 }
 
-func randBetween(start, end uint64) uint64 {
-	return start + uint64(rand.Int63n(int64(end-start)))
+func randBetweenDur(rng *rand.Rand, start, end time.Duration) time.Duration {
+	return (start + time.Duration(rng.Int63n(int64(end-start)))).Round(time.Second)
+}
+
+func randBetween(rng *rand.Rand, start, end uint64) uint64 {
+	return start + uint64(rng.Int63n(int64(end-start)))
 }
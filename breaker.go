@@ -0,0 +1,290 @@
+package chainload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/gochain/gochain/v3"
+	"github.com/gochain/gochain/v3/common"
+	"github.com/gochain/gochain/v3/core/types"
+	metrics "github.com/rcrowley/go-metrics"
+	"go.uber.org/zap"
+)
+
+// errBreakerOpen is returned without calling the node while its breaker is open.
+var errBreakerOpen = errors.New("circuit breaker open")
+
+var (
+	breakerOpenMeter     = metrics.GetOrRegisterMeter("meter/breaker/open", nil)
+	breakerHalfOpenMeter = metrics.GetOrRegisterMeter("meter/breaker/halfOpen", nil)
+	breakerClosedMeter   = metrics.GetOrRegisterMeter("meter/breaker/closed", nil)
+	breakerRejectMeter   = metrics.GetOrRegisterMeter("meter/breaker/reject", nil)
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerClosed:
+		return "closed"
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "halfOpen"
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
+// breaker is a circuit breaker shared by all goroutines calling a node. It
+// opens when the error rate over the last window calls reaches rate, then fails
+// fast until cooldown has passed, after which a single probe call is allowed
+// through (half-open) to decide whether to close or re-open.
+type breaker struct {
+	lgr      *zap.Logger
+	rate     float64
+	cooldown time.Duration
+	gauge    metrics.Gauge // Current state.
+
+	mu       sync.Mutex
+	state    breakerState
+	results  []bool // Circular buffer of recent call failures.
+	idx      int
+	count    int // Results recorded, up to len(results).
+	failures int // Failures in results.
+	openedAt time.Time
+	probing  bool
+}
+
+func newBreaker(lgr *zap.Logger, node int, rate float64, window int, cooldown time.Duration) *breaker {
+	return &breaker{
+		lgr:      lgr,
+		rate:     rate,
+		cooldown: cooldown,
+		results:  make([]bool, window),
+		gauge:    metrics.GetOrRegisterGauge(fmt.Sprintf("gauge/breaker/node/%d", node), nil),
+	}
+}
+
+// State returns the current state.
+func (b *breaker) State() breakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// allow returns errBreakerOpen if a call must not be made.
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			breakerRejectMeter.Mark(1)
+			return errBreakerOpen
+		}
+		b.transition(breakerHalfOpen)
+		b.probing = true
+		return nil
+	case breakerHalfOpen:
+		if b.probing {
+			breakerRejectMeter.Mark(1)
+			return errBreakerOpen
+		}
+		b.probing = true
+		return nil
+	}
+	return nil
+}
+
+// record records the result of an allowed call.
+func (b *breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerHalfOpen:
+		b.probing = false
+		if failed {
+			b.open()
+		} else {
+			b.reset()
+			b.transition(breakerClosed)
+		}
+	case breakerClosed:
+		if b.count == len(b.results) {
+			if b.results[b.idx] {
+				b.failures--
+			}
+		} else {
+			b.count++
+		}
+		b.results[b.idx] = failed
+		b.idx = (b.idx + 1) % len(b.results)
+		if failed {
+			b.failures++
+		}
+		if b.count == len(b.results) && float64(b.failures)/float64(b.count) >= b.rate {
+			b.open()
+		}
+	}
+}
+
+// cancel releases an allowed call which was abandoned without a result.
+func (b *breaker) cancel() {
+	b.mu.Lock()
+	if b.state == breakerHalfOpen {
+		b.probing = false
+	}
+	b.mu.Unlock()
+}
+
+func (b *breaker) open() {
+	b.openedAt = time.Now()
+	b.reset()
+	b.transition(breakerOpen)
+}
+
+func (b *breaker) reset() {
+	b.idx, b.count, b.failures = 0, 0, 0
+}
+
+func (b *breaker) transition(to breakerState) {
+	if b.state == to {
+		return
+	}
+	b.lgr.Warn("Circuit breaker transition", zap.Stringer("from", b.state), zap.Stringer("to", to))
+	b.state = to
+	b.gauge.Update(int64(to))
+	switch to {
+	case breakerOpen:
+		breakerOpenMeter.Mark(1)
+	case breakerHalfOpen:
+		breakerHalfOpenMeter.Mark(1)
+	case breakerClosed:
+		breakerClosedMeter.Mark(1)
+	}
+}
+
+// breakerClient wraps a Client with a breaker. Only transport errors (timeouts,
+// connection failures, rate limiting, server errors) count as failures.
+type breakerClient struct {
+	Client
+	b    *breaker
+	errs *errClassifier
+}
+
+func (c *breakerClient) call(ctx context.Context, fn func() error) error {
+	if err := c.b.allow(); err != nil {
+		return err
+	}
+	err := fn()
+	if ctx.Err() != nil {
+		c.b.cancel()
+	} else {
+		c.b.record(err != nil && c.errs.classify(err).transport())
+	}
+	return err
+}
+
+func (c *breakerClient) LatestBlockNumber(ctx context.Context) (n *big.Int, err error) {
+	err = c.call(ctx, func() (err error) {
+		n, err = c.Client.LatestBlockNumber(ctx)
+		return
+	})
+	return
+}
+
+func (c *breakerClient) SuggestGasPrice(ctx context.Context) (p *big.Int, err error) {
+	err = c.call(ctx, func() (err error) {
+		p, err = c.Client.SuggestGasPrice(ctx)
+		return
+	})
+	return
+}
+
+func (c *breakerClient) PendingBalanceAt(ctx context.Context, account common.Address) (b *big.Int, err error) {
+	err = c.call(ctx, func() (err error) {
+		b, err = c.Client.PendingBalanceAt(ctx, account)
+		return
+	})
+	return
+}
+
+func (c *breakerClient) PendingNonceAt(ctx context.Context, account common.Address) (n uint64, err error) {
+	err = c.call(ctx, func() (err error) {
+		n, err = c.Client.PendingNonceAt(ctx, account)
+		return
+	})
+	return
+}
+
+func (c *breakerClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (n uint64, err error) {
+	err = c.call(ctx, func() (err error) {
+		n, err = c.Client.NonceAt(ctx, account, blockNumber)
+		return
+	})
+	return
+}
+
+func (c *breakerClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	return c.call(ctx, func() error {
+		return c.Client.SendTransaction(ctx, tx)
+	})
+}
+
+func (c *breakerClient) TransactionReceipt(ctx context.Context, hash common.Hash) (r *types.Receipt, err error) {
+	err = c.call(ctx, func() (err error) {
+		r, err = c.Client.TransactionReceipt(ctx, hash)
+		return
+	})
+	return
+}
+
+func (c *breakerClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (b *big.Int, err error) {
+	err = c.call(ctx, func() (err error) {
+		b, err = c.Client.BalanceAt(ctx, account, blockNumber)
+		return
+	})
+	return
+}
+
+func (c *breakerClient) HeaderByNumber(ctx context.Context, number *big.Int) (h *types.Header, err error) {
+	err = c.call(ctx, func() (err error) {
+		h, err = c.Client.HeaderByNumber(ctx, number)
+		return
+	})
+	return
+}
+
+func (c *breakerClient) BlockByNumber(ctx context.Context, number *big.Int) (b *types.Block, err error) {
+	err = c.call(ctx, func() (err error) {
+		b, err = c.Client.BlockByNumber(ctx, number)
+		return
+	})
+	return
+}
+
+func (c *breakerClient) CallContract(ctx context.Context, msg gochain.CallMsg, blockNumber *big.Int) (out []byte, err error) {
+	err = c.call(ctx, func() (err error) {
+		out, err = c.Client.CallContract(ctx, msg, blockNumber)
+		return
+	})
+	return
+}
+
+func (c *breakerClient) FilterLogs(ctx context.Context, q gochain.FilterQuery) (logs []types.Log, err error) {
+	err = c.call(ctx, func() (err error) {
+		logs, err = c.Client.FilterLogs(ctx, q)
+		return
+	})
+	return
+}
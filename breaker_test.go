@@ -0,0 +1,81 @@
+package chainload
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/gochain/gochain/v3/core/types"
+	"github.com/gochain/gochain/v3/params"
+	"go.uber.org/zap"
+)
+
+func Test_breaker(t *testing.T) {
+	b := newBreaker(zap.NewNop(), -1, 0.5, 4, 50*time.Millisecond)
+	for _, failed := range []bool{false, true, false, true} {
+		if err := b.allow(); err != nil {
+			t.Fatal(err)
+		}
+		b.record(failed)
+	}
+	if s := b.State(); s != breakerOpen {
+		t.Fatalf("expected open but got %s", s)
+	}
+	if err := b.allow(); err != errBreakerOpen {
+		t.Fatalf("expected errBreakerOpen but got %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected probe to be allowed: %v", err)
+	}
+	if s := b.State(); s != breakerHalfOpen {
+		t.Fatalf("expected halfOpen but got %s", s)
+	}
+	if err := b.allow(); err != errBreakerOpen {
+		t.Fatalf("expected concurrent probe to be rejected but got %v", err)
+	}
+	b.record(true)
+	if s := b.State(); s != breakerOpen {
+		t.Fatalf("expected failed probe to re-open but got %s", s)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected probe to be allowed: %v", err)
+	}
+	b.record(false)
+	if s := b.State(); s != breakerClosed {
+		t.Fatalf("expected successful probe to close but got %s", s)
+	}
+}
+
+func TestNode_sendTxBreakerOpen(t *testing.T) {
+	mock, node, accts, cleanup := newTestNode(t, 1e18)
+	defer cleanup()
+	if err := node.unlock(accts[0]); err != nil {
+		t.Fatal(err)
+	}
+	b := newBreaker(zap.NewNop(), -1, 0.5, 1, time.Hour)
+	b.record(true)
+	node.Client = &breakerClient{Client: node.Client, b: b, errs: node.errs}
+
+	tx, err := node.SignTx(accts[0], types.NewTransaction(0, accts[0].Address, new(big.Int), params.TxGas, big.NewInt(1), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs, rej := sendTxErrMeter.Count(), sendTxRejectedMeter.Count()
+	if err := node.sendTx(context.Background(), senderRole, accts[0].Address, tx); err != errBreakerOpen {
+		t.Fatalf("expected errBreakerOpen but got %v", err)
+	}
+	if n := sendTxErrMeter.Count() - errs; n != 0 {
+		t.Errorf("expected no send errors but got %d", n)
+	}
+	if n := sendTxRejectedMeter.Count() - rej; n != 1 {
+		t.Errorf("expected 1 rejected send but got %d", n)
+	}
+	if n := mock.Calls("eth_sendRawTransaction"); n != 0 {
+		t.Errorf("expected no calls but got %d", n)
+	}
+}
@@ -9,10 +9,12 @@ import (
 	"os/signal"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/gochain/gochain/v3/accounts/keystore"
+	"github.com/gochain/gochain/v3/common"
 	"github.com/gochain/gochain/v3/goclient"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -30,6 +32,59 @@ type Config struct {
 	Amount    uint64
 	PprofAddr string
 	Variable  time.Duration
+
+	Keystore  string // Keystore directory.
+	Scrypt    string // Keystore encryption: "none" (plaintext), "light" or "standard".
+	Unlockers int    // Concurrent account unlocks at start up, for encrypted keystores.
+
+	SeedMax  *big.Int // Maximum funds sent per seed request. Optional.
+	Budget   *big.Int // Maximum wei to spend on gas. Optional.
+	Receipts int      // Receipt polling goroutines per node, for gas accounting.
+	Reclaim  time.Duration
+
+	Errors     string // JSON file of additional error patterns by class. Optional.
+	NonceCheck time.Duration
+	Stuck      time.Duration
+	PriceBump  uint64 // Minimum replacement gas price increase, in percent.
+
+	Breaker         float64 // Node error rate which opens its circuit breaker. 0 disables.
+	BreakerWindow   int     // Calls over which the error rate is measured.
+	BreakerCooldown time.Duration
+
+	Reads       int    // Read requests per second. 0 disables.
+	ReadMethods string // CSV of read methods.
+	LogsRange   uint64 // Blocks per eth_getLogs request.
+	Contract    string // Address of a previously deployed test contract. Optional.
+
+	WsUrlsCSV   string  // WebSocket urls to subscribe to. Optional.
+	WsConns     int     // Connections per WebSocket url.
+	WsSubs      string  // CSV of subscription types.
+	ContractTxs float64 // Fraction of sender txs which call the test contract.
+
+	Invalid      float64 // Fraction of sender txs which are deliberately invalid.
+	InvalidKinds string  // CSV of invalid tx kinds.
+
+	DataMin  int    // Minimum calldata bytes per sender tx.
+	DataMax  int    // Maximum calldata bytes per sender tx. 0 disables.
+	DataDist string // Calldata size distribution: uniform or exp.
+
+	Seed int64 // Seeds all randomness. Derived from the time if 0.
+
+	DryRun bool // Build and sign txs, but never send them.
+
+	Record string // File to record sent txs to, for replay. Optional.
+
+	ReportInterval time.Duration
+	Timeseries     string // File to append every interval report to, as CSV or JSON lines. Optional.
+	Out            string // File to write the results of the run to, as JSON. Optional.
+	JUnit          string // File to write stages and SLO checks to, as JUnit XML. Optional.
+
+	TUI    bool   // Draw a live dashboard to stdout, in place of status logs.
+	TUILog string // File logs are written to with TUI, shown on the dashboard.
+
+	SLO SLO // Pass criteria. Run returns an error if any are violated.
+
+	Version string // Recorded in the results.
 }
 
 func (c *Config) MarshalLogObject(oe zapcore.ObjectEncoder) error {
@@ -44,6 +99,48 @@ func (c *Config) MarshalLogObject(oe zapcore.ObjectEncoder) error {
 	oe.AddUint64("amount", c.Amount)
 	oe.AddString("pprofAddr", c.PprofAddr)
 	oe.AddDuration("variable", c.Variable)
+	oe.AddString("keystore", c.Keystore)
+	oe.AddString("scrypt", c.Scrypt)
+	oe.AddInt("unlockers", c.Unlockers)
+	if c.SeedMax != nil {
+		addBigField(oe, "seedMax", c.SeedMax)
+	}
+	if c.Budget != nil {
+		addBigField(oe, "budget", c.Budget)
+	}
+	oe.AddInt("receipts", c.Receipts)
+	oe.AddDuration("reclaim", c.Reclaim)
+	oe.AddString("errors", c.Errors)
+	oe.AddDuration("nonceCheck", c.NonceCheck)
+	oe.AddDuration("stuck", c.Stuck)
+	oe.AddUint64("priceBump", c.PriceBump)
+	oe.AddFloat64("breaker", c.Breaker)
+	oe.AddInt("breakerWindow", c.BreakerWindow)
+	oe.AddDuration("breakerCooldown", c.BreakerCooldown)
+	oe.AddInt("reads", c.Reads)
+	oe.AddString("readMethods", c.ReadMethods)
+	oe.AddUint64("logsRange", c.LogsRange)
+	oe.AddString("contract", c.Contract)
+	oe.AddString("wsUrls", c.WsUrlsCSV)
+	oe.AddInt("wsConns", c.WsConns)
+	oe.AddString("wsSubs", c.WsSubs)
+	oe.AddFloat64("contractTxs", c.ContractTxs)
+	oe.AddFloat64("invalid", c.Invalid)
+	oe.AddString("invalidKinds", c.InvalidKinds)
+	oe.AddInt("dataMin", c.DataMin)
+	oe.AddInt("dataMax", c.DataMax)
+	oe.AddString("dataDist", c.DataDist)
+	oe.AddInt64("seed", c.Seed)
+	oe.AddBool("dryRun", c.DryRun)
+	oe.AddString("record", c.Record)
+	oe.AddDuration("reportInterval", c.ReportInterval)
+	oe.AddString("timeseries", c.Timeseries)
+	oe.AddString("out", c.Out)
+	oe.AddString("junit", c.JUnit)
+	oe.AddBool("tui", c.TUI)
+	if c.SLO.enabled() {
+		oe.AddObject("slo", &c.SLO)
+	}
 	return nil
 }
 
@@ -51,6 +148,26 @@ type Chainload struct {
 	config *Config
 	lgr    *zap.Logger
 	nodes  []*Node
+	as     *AccountStore
+
+	readMethods []string
+	contract    *common.Address // Test contract. Deployed by Run if nil and needed.
+	wsURLs      []string
+	wsSubs      []string
+	invalid     []string // Invalid tx kinds.
+	data        *dataDist
+
+	rng *rand.Rand // Seeds the rng of each component, in a fixed order.
+
+	null *nullClient // Shared by every node in a dry run.
+
+	record  *recorder   // Optional.
+	ts      *timeseries // Optional.
+	results *runResults // Optional.
+	start   time.Time
+	stages  []*stage
+	checks  sloResult
+	load    time.Duration // Time spent sending.
 }
 
 func (config *Config) NewChainload(lgr *zap.Logger) (*Chainload, error) {
@@ -61,87 +178,344 @@ func (config *Config) NewChainload(lgr *zap.Logger) (*Chainload, error) {
 		config.Senders = config.TPS
 	}
 
-	lgr.Info("Opening keystore...")
+	if config.ReportInterval <= 0 {
+		config.ReportInterval = 30 * time.Second
+	}
+
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+		lgr.Info("Derived random seed", zap.Int64("seed", config.Seed))
+	}
+	rng := rand.New(rand.NewSource(config.Seed))
+
+	if config.SLO.needsReceipts() && config.Receipts == 0 {
+		return nil, fmt.Errorf("inclusion latency and dropped tx SLOs require receipt polling")
+	}
+
+	if config.DryRun {
+		// Nothing to confirm, and no network to check or subscribe to.
+		config.Receipts, config.NonceCheck, config.Stuck, config.Breaker = 0, 0, 0, 0
+		config.WsUrlsCSV = ""
+		// Nothing sent, so nothing to replay.
+		config.Record = ""
+		lgr.Info("Dry run: txs will be signed but not sent")
+	}
+
+	if config.Keystore == "" {
+		config.Keystore = "keystore"
+	}
+	lgr.Info("Opening keystore...", zap.String("dir", config.Keystore))
 	start := time.Now()
-	as := NewAccountStore(keystore.NewPlaintextKeyStore("keystore"), new(big.Int).SetUint64(config.Id), config.Password)
+	ks, err := openKeyStore(config.Keystore, config.Scrypt)
+	if err != nil {
+		return nil, err
+	}
+	as, err := NewAccountStore(ks, new(big.Int).SetUint64(config.Id), config.Password, statePath(config.Keystore))
+	if err != nil {
+		return nil, err
+	}
 	lgr.Info("Keystore opened", zap.Duration("duration", time.Since(start)))
+	errs, err := newErrClassifier(config.Errors)
+	if err != nil {
+		return nil, err
+	}
+	readMethods, err := parseReadMethods(config.ReadMethods)
+	if err != nil {
+		return nil, err
+	}
+	if config.Reads > 0 && len(readMethods) == 0 {
+		return nil, fmt.Errorf("illegal read methods argument: %q", config.ReadMethods)
+	}
+	var contract *common.Address
+	if config.Contract != "" {
+		if !common.IsHexAddress(config.Contract) {
+			return nil, fmt.Errorf("illegal contract argument: %q", config.Contract)
+		}
+		addr := common.HexToAddress(config.Contract)
+		contract = &addr
+	}
+	var wsURLs, wsSubs []string
+	if config.WsUrlsCSV != "" && config.WsConns > 0 {
+		wsURLs = strings.Split(config.WsUrlsCSV, ",")
+		wsSubs, err = parseSubTypes(config.WsSubs)
+		if err != nil {
+			return nil, err
+		}
+		if len(wsSubs) == 0 {
+			return nil, fmt.Errorf("illegal ws subscriptions argument: %q", config.WsSubs)
+		}
+	}
+	if config.ContractTxs < 0 || config.ContractTxs > 1 {
+		return nil, fmt.Errorf("illegal contract txs argument: %v", config.ContractTxs)
+	}
+	var invalid []string
+	if config.Invalid > 0 {
+		if config.Invalid > 1 {
+			return nil, fmt.Errorf("illegal invalid argument: %v", config.Invalid)
+		}
+		invalid, err = parseInvalidKinds(config.InvalidKinds)
+		if err != nil {
+			return nil, err
+		}
+		if len(invalid) == 0 {
+			return nil, fmt.Errorf("illegal invalid kinds argument: %q", config.InvalidKinds)
+		}
+	}
+	var data *dataDist
+	if config.DataMax > 0 {
+		data, err = newDataDist(config.DataMin, config.DataMax, config.DataDist)
+		if err != nil {
+			return nil, err
+		}
+	}
 	urls := strings.Split(config.UrlsCSV, ",")
 
+	var null *nullClient
+	if config.DryRun {
+		null = newNullClient()
+	}
 	var nodes []*Node
 	for i := range urls {
 		url := urls[i]
-		client, err := goclient.Dial(url)
-		if err != nil {
-			lgr.Warn("Failed to dial", zap.String("url", url), zap.Error(err))
+		if null != nil {
+			nodes = append(nodes, &Node{
+				lgr:          lgr.With(zap.Int("node", i), zap.String("url", url)),
+				Number:       i,
+				gas:          config.Gas,
+				Client:       &latencyClient{Client: null, node: i},
+				AccountStore: as,
+				SeedCh:       make(chan SeedReq),
+				errs:         errs,
+			})
 			continue
 		}
-		chainID, err := client.ChainID(context.Background())
-		if err != nil {
-			lgr.Warn("Failed to check chain ID", zap.String("url", url), zap.Error(err))
-			continue
-		} else if chainID == nil || config.Id != chainID.Uint64() {
-			lgr.Warn("Wrong chain ID", zap.Uint64("configID", config.Id),
-				zap.Uint64("nodeID", chainID.Uint64()), zap.String("url", url), zap.Error(err))
+		client, ok := dialNode(lgr, url, config.Id)
+		if !ok {
 			continue
 		}
-		nodes = append(nodes, &Node{
+		node := &Node{
 			lgr:          lgr.With(zap.Int("node", i), zap.String("url", url)),
 			Number:       i,
 			gas:          config.Gas,
 			Client:       client,
 			AccountStore: as,
 			SeedCh:       make(chan SeedReq),
-		})
+			errs:         errs,
+		}
+		if config.Breaker > 0 {
+			if config.BreakerWindow < 1 {
+				config.BreakerWindow = 1
+			}
+			node.breaker = newBreaker(node.lgr, i, config.Breaker, config.BreakerWindow, config.BreakerCooldown)
+			node.Client = &breakerClient{Client: client, b: node.breaker, errs: errs}
+		}
+		node.Client = &latencyClient{Client: node.Client, node: i}
+		if config.Receipts > 0 {
+			node.receipts = make(chan *pendingReceipt, 10000)
+		}
+		nodes = append(nodes, node)
 	}
 	if len(nodes) == 0 {
 		return nil, fmt.Errorf("failed to dial all %d urls: %v", len(urls), urls)
 	}
-	return &Chainload{config: config, lgr: lgr, nodes: nodes}, nil
+	var record *recorder
+	if config.Record != "" {
+		record, err = newRecorder(config.Record, config.Id)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			n.record = record
+		}
+		lgr.Info("Recording sent txs", zap.String("file", config.Record))
+	}
+	var ts *timeseries
+	if config.Timeseries != "" {
+		ts, err = newTimeseries(config.Timeseries)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Chainload{config: config, lgr: lgr, nodes: nodes, as: as, readMethods: readMethods, contract: contract,
+		wsURLs: wsURLs, wsSubs: wsSubs, invalid: invalid, data: data, rng: rng, null: null, record: record, ts: ts}, nil
+}
+
+// dialNode dials url and checks that it serves chain id. Failures are logged.
+func dialNode(lgr *zap.Logger, url string, id uint64) (*goclient.Client, bool) {
+	client, err := goclient.Dial(url)
+	if err != nil {
+		lgr.Warn("Failed to dial", zap.String("url", url), zap.Error(err))
+		return nil, false
+	}
+	chainID, err := client.ChainID(context.Background())
+	if err != nil {
+		lgr.Warn("Failed to check chain ID", zap.String("url", url), zap.Error(err))
+		return nil, false
+	} else if chainID == nil || id != chainID.Uint64() {
+		lgr.Warn("Wrong chain ID", zap.Uint64("configID", id),
+			zap.Uint64("nodeID", chainID.Uint64()), zap.String("url", url), zap.Error(err))
+		return nil, false
+	}
+	return client, true
+}
+
+// openKeyStore opens the keystore in dir with the given encryption, one of
+// "none" (plaintext), "light" or "standard" scrypt parameters.
+func openKeyStore(dir, scrypt string) (*keystore.KeyStore, error) {
+	switch scrypt {
+	case "", "none":
+		return keystore.NewPlaintextKeyStore(dir), nil
+	case "light":
+		return keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP), nil
+	case "standard":
+		return keystore.NewKeyStore(dir, keystore.StandardScryptN, keystore.StandardScryptP), nil
+	default:
+		return nil, fmt.Errorf("illegal scrypt argument: %q", scrypt)
+	}
 }
 
-func (c *Chainload) Run() error {
+func (c *Chainload) Run() (err error) {
+	c.start = time.Now()
+	c.stages = newStages()
+	if c.config.JUnit != "" {
+		defer func() { c.writeJUnit(err) }()
+	}
+	seedersStage, sendersStage, loadStage := c.stages[0], c.stages[1], c.stages[2]
+	seedersStage.begin()
+	if c.config.Out != "" {
+		c.results = newRunResults(c.config, c.start)
+		c.results.Version = c.config.Version
+	}
 	ctx, cancelFn := context.WithCancel(context.Background())
+	// Receipts are still polled after the load stops, until drained.
+	receiptsCtx, stopReceipts := context.WithCancel(context.Background())
+	defer stopReceipts()
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		for sig := range sigCh {
 			c.lgr.Info("Signal received. Stopping...", zap.String("signal", sig.String()))
+			if ctx.Err() != nil {
+				// Stopping already, so stop waiting for receipts too.
+				stopReceipts()
+			}
 			cancelFn()
 		}
 	}()
 
+	if c.record != nil {
+		defer func() {
+			if err := c.record.Close(); err != nil {
+				c.lgr.Warn("Failed to write record file", zap.Error(err))
+			}
+		}()
+	}
+	if c.ts != nil {
+		defer func() {
+			if err := c.ts.Close(); err != nil {
+				c.lgr.Warn("Failed to write timeseries file", zap.Error(err))
+			}
+		}()
+	}
+
+	var ready int64 // Senders assigned an account and funded.
+	var dash *dashboard
+	if c.config.TUI {
+		dash = newDashboard(os.Stdout, c, &ready)
+		dashCtx, stopDash := context.WithCancel(context.Background())
+		var dashWG sync.WaitGroup
+		dashWG.Add(1)
+		go dash.run(dashCtx, dashWG.Done)
+		defer func() {
+			stopDash()
+			dashWG.Wait()
+		}()
+	}
+
+	if c.config.Scrypt != "" && c.config.Scrypt != "none" {
+		// Just the seeders and senders, and interruptible, since encrypted keys
+		// are slow to decrypt.
+		c.lgr.Info("Unlocking accounts...", zap.Int("unlockers", c.config.Unlockers))
+		start := time.Now()
+		n := c.as.Unlock(ctx, c.lgr, c.config.Unlockers, len(c.nodes)+c.config.Senders)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		c.lgr.Info("Accounts unlocked", zap.Int("count", n), zap.Duration("duration", time.Since(start)))
+	}
+
 	var wg sync.WaitGroup
-	var seeders int
+	var seeders []*Seeder
+	var dataGas uint64
+	if c.data != nil {
+		dataGas = c.data.maxGas()
+	}
 	for _, node := range c.nodes {
-		acct, err := node.NextSeed()
+		acct, nonce, err := node.NextSeed()
 		if err != nil {
 			c.lgr.Warn("Failed to get seeder account", zap.Error(err))
 		}
 		if err != nil || acct == nil {
+			nonce = 0
 			acct, err = node.New(ctx)
 			if err != nil {
 				c.lgr.Warn("Failed to create new seeder account", zap.Error(err))
 				continue
 			}
 		}
-		s := &Seeder{
-			Node: node,
-			acct: acct,
+		if c.null != nil {
+			c.null.fund(acct.Address)
 		}
-		wg.Add(1)
-		seeders++
-		go s.Run(ctx, wg.Done)
+		seeders = append(seeders, &Seeder{
+			rng:     newRand(c.rng),
+			Node:    node,
+			acct:    acct,
+			nonce:   nonce,
+			maxSeed: c.config.SeedMax,
+			dataGas: dataGas,
+		})
 	}
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
-	if seeders == 0 {
+	if len(seeders) == 0 {
 		return fmt.Errorf("failed to create any seeders for %d nodes", len(c.nodes))
 	}
-	c.lgr.Info("Started seeders", zap.Int("count", seeders))
+	if c.contract == nil && c.needContract() {
+		addr, err := seeders[0].deployTestContract(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.lgr.Warn("Failed to deploy test contract", zap.Error(err))
+		} else {
+			c.contract = &addr
+		}
+	}
+	for _, s := range seeders {
+		wg.Add(1)
+		go s.Run(ctx, wg.Done)
+	}
+	c.lgr.Info("Started seeders", zap.Int("count", len(seeders)))
+	seedersStage.finish("")
+
+	if dash != nil {
+		dash.setSeeders(seeders)
+	}
+
+	var receiptsWG sync.WaitGroup
+	drainReceipts := make(chan struct{})
+	for _, node := range c.nodes {
+		for i := 0; i < c.config.Receipts; i++ {
+			receiptsWG.Add(1)
+			go node.watchReceipts(receiptsCtx, drainReceipts, receiptsWG.Done)
+		}
+	}
 
 	start := time.Now()
 	c.lgr.Info("Starting senders", zap.Int("count", c.config.Senders))
+	sendersStage.begin()
+	loadStage.begin()
 	stats := NewReporter()
 	if c.config.Duration != 0 {
 		t := time.AfterFunc(c.config.Duration, func() {
@@ -150,29 +524,37 @@ func (c *Chainload) Run() error {
 		defer t.Stop()
 	}
 
+	// Scheduling jitter.
+	sched, variable := newRand(c.rng), newRand(c.rng)
+
 	wg.Add(c.config.Senders)
-	txsIn := make(chan struct{}, c.config.TPS*10)
+	txsIn := make(chan time.Time, c.config.TPS*10) // Scheduled send times.
 	txsOut := txsIn
 
 	if c.config.Variable > 0 {
 		// Spawn a goroutine to intercept released txs and sporadically delay them to vary the rate.
-		txsOut = make(chan struct{}, c.config.TPS)
+		txsOut = make(chan time.Time, c.config.TPS)
 		go func() {
 			defer close(txsOut)
 			nextPause := time.Now()
+			var resumed time.Time // End of the last pause, which delays the schedule.
 			for {
 				select {
 				case <-ctx.Done():
 					return
 				case tx := <-txsIn:
 					if time.Until(nextPause) <= 0 {
-						wait := randBetweenDur(0, c.config.Variable)
+						wait := randBetweenDur(variable, 0, c.config.Variable)
 						select {
 						case <-ctx.Done():
 							return
 						case <-time.After(wait):
 						}
-						nextPause = time.Now().Add(randBetweenDur(c.config.Variable/2, c.config.Variable))
+						resumed = time.Now()
+						nextPause = resumed.Add(randBetweenDur(variable, c.config.Variable/2, c.config.Variable))
+					}
+					if !tx.IsZero() && tx.Before(resumed) {
+						tx = resumed
 					}
 					txsOut <- tx
 				}
@@ -186,60 +568,365 @@ func (c *Chainload) Run() error {
 		tpsLimit = 1
 	}
 
-	for num := 0; num < c.config.Senders; num++ {
+	allReady := make(chan struct{})
+	senders := make([]*Sender, c.config.Senders)
+	for num := range senders {
 		node := num % len(c.nodes)
-		s := Sender{
+		s := &Sender{
+			rng:       newRand(c.rng),
 			Number:    num,
 			amount:    c.config.Amount,
 			cycle:     c.config.Cycle,
 			Node:      c.nodes[node],
 			RateLimit: time.Second / time.Duration(tpsLimit),
+
+			nonceCheck: c.config.NonceCheck,
+			stuckAfter: c.config.Stuck,
+			priceBump:  c.config.PriceBump,
+
+			contract:    c.contract,
+			contractTxs: c.config.ContractTxs,
+
+			invalid:      c.config.Invalid,
+			invalidKinds: c.invalid,
+
+			data: c.data,
+
+			ready: func() {
+				if atomic.AddInt64(&ready, 1) == int64(c.config.Senders) {
+					sendersStage.finish("")
+					close(allReady)
+					c.lgr.Info("All senders ready", zap.Duration("duration", time.Since(start)))
+				}
+			},
 		}
+		senders[num] = s
 		go s.Send(ctx, txsOut, wg.Done)
 	}
 
-	// 1/10 second batches, with reports every 30s.
+	if c.config.Reads > 0 {
+		c.startReaders(ctx, &wg)
+	}
+	if len(c.wsURLs) > 0 {
+		c.startSubscribers(ctx, &wg)
+	}
+
+	// 1/10 second batches, with periodic reports. Each batch is due at a fixed
+	// offset from the first, rather than at whenever its tick is received, since
+	// ticks are dropped while blocked on a full txsIn. The schedule starts once
+	// every sender is ready: batches before then are unscheduled (zero), since
+	// start-up is not the latency of the load.
 	const batchCount = 10
+	due := time.Now().Add(time.Second / batchCount) // Never after the first tick.
+	var scheduled bool
 	batch := time.NewTicker(time.Second / batchCount)
-	report := time.NewTicker(30 * time.Second)
+	report := time.NewTicker(c.config.ReportInterval)
 	defer batch.Stop()
 	defer report.Stop()
 
 	batches := make([]int, batchCount)
 	distribute(c.config.TPS, batches)
-	rand.Shuffle(len(batches), func(i, j int) {
+	sched.Shuffle(len(batches), func(i, j int) {
 		batches[i], batches[j] = batches[j], batches[i]
 	})
 
+	var slo *sloTracker
+	if c.config.SLO.enabled() {
+		slo = newSLOTracker(&c.config.SLO, c.config.TPS)
+	}
 	var reports Reports
 	var cnt int
+	var readyAt time.Time // When every sender was ready, if they were.
+	var readyTxs, readyErrs int64
 loop:
 	for {
 		select {
 		case <-ctx.Done():
 			break loop
+		case <-allReady:
+			allReady = nil
+			scheduled = true
+			due = time.Now().Add(time.Second / batchCount)
+			// Start-up is not checked against the SLOs, so it ends a report.
+			reports.Add(c.report(stats))
+			readyAt, readyTxs, readyErrs = time.Now(), reports.total.txs, reports.total.errs
 		case <-report.C:
-			s := reports.Add(stats.Report())
+			r := c.report(stats)
+			s := reports.Add(r)
 			c.lgr.Info("Status", zap.Object("status", s))
-		case <-batch.C:
-			batchSize := batches[cnt%len(batches)]
-			for i := 0; i < batchSize; i++ {
-				txsIn <- struct{}{}
+			if slo != nil && c.config.SLO.Intervals && scheduled {
+				if failed := slo.interval(r); len(failed) > 0 {
+					c.lgr.Warn("SLO violated", zap.Object("slo", sloResult(failed)))
+				}
+			}
+		case now := <-batch.C:
+			if c.config.Budget != nil {
+				if total := spent.Total(); total.Cmp(c.config.Budget) >= 0 {
+					c.lgr.Info("Budget reached. Stopping...", zapBig("spent", total), zapBig("budget", c.config.Budget))
+					break loop
+				}
+			}
+			// Every batch now due, including any whose ticks were dropped.
+			for ; !due.After(now); cnt++ {
+				var at time.Time
+				if scheduled {
+					at = due
+				}
+				for i := 0; i < batches[cnt%len(batches)]; i++ {
+					select {
+					case txsIn <- at:
+					case <-ctx.Done():
+						break loop
+					}
+				}
+				due = due.Add(time.Second / batchCount)
 			}
-			cnt++
 		}
 	}
+	loadEnd := time.Now()
+	loadStage.finish("")
+	c.load = loadEnd.Sub(start)
 	close(txsIn)
 	cancelFn()
 	wg.Wait()
+	sendersStage.finish(fmt.Sprintf("only %d of %d senders were ready", atomic.LoadInt64(&ready), c.config.Senders))
+	reports.Add(c.report(stats))
+	loadDur := reports.total.dur
+	if c.config.Receipts > 0 {
+		c.drainReceipts(drainReceipts, stopReceipts, &receiptsWG)
+		reports.Add(c.report(stats))
+	}
+	// The load, without reclaiming: its refunds are not throughput, and its
+	// time would dilute it. Nor would the time spent draining receipts.
+	load := reports.totalReport()
+	load.dur = loadDur
 
-	s := reports.Add(stats.Report())
+	var reclaimed reclaimSummary
+	if c.config.Reclaim > 0 {
+		reclaimed = c.reclaim(senders)
+	} else {
+		for _, s := range senders {
+			if s.acct != nil {
+				s.AccountStore.Return(s.acct, s.Node.Number, s.nonce)
+			}
+		}
+	}
+	if c.null != nil {
+		// Simulated nonces and balances would mislead the next run.
+		c.lgr.Info("Dry run: not saving account state")
+	} else if err := c.as.Save(); err != nil {
+		c.lgr.Warn("Failed to save account state", zap.Error(err))
+	}
+
+	s := reports.Add(c.report(stats))
 	end := time.Now()
-	c.lgr.Info("Final Status", zap.Object("status", s), zap.Time("start", start), zap.Time("end", end))
+	if c.results != nil {
+		c.writeResults(load, end)
+	}
+	fields := []zap.Field{zap.Object("status", s), zap.Object("reclaimed", &reclaimed),
+		zap.Time("start", start), zap.Time("end", end)}
+	if slo != nil {
+		// Throughput and error rate from when every sender was ready.
+		r, dur := *load, c.load
+		if !readyAt.IsZero() {
+			r.txs, r.errs = r.txs-readyTxs, r.errs-readyErrs
+			dur = loadEnd.Sub(readyAt)
+		}
+		c.checks = slo.result(&r, dur)
+		fields = append(fields, zap.Object("slo", c.checks))
+	}
+	c.lgr.Info("Final Status", fields...)
+	return c.checks.err()
+}
+
+// drainReceipts waits for the receipt pollers to find or time out every tx of
+// the load, then stops them. Txs left pending, or never polled, are counted as
+// unconfirmed. Receipts of later txs, like refunds, are not polled.
+func (c *Chainload) drainReceipts(drain chan struct{}, stop func(), wg *sync.WaitGroup) {
+	c.lgr.Info("Waiting for receipts", zap.Duration("timeout", receiptTimeout+receiptDelay))
+	close(drain)
+	t := time.AfterFunc(receiptTimeout+receiptDelay, stop)
+	wg.Wait()
+	t.Stop()
+	stop()
+	for _, node := range c.nodes {
+		receiptsUnconfirmedMeter.Mark(int64(len(node.receipts)))
+		node.receipts = nil
+	}
+}
+
+// report returns the next report from stats, and adds it to the time series
+// and results.
+func (c *Chainload) report(stats Reporter) *Report {
+	r := stats.Report()
+	if c.ts == nil && c.results == nil {
+		return r
+	}
+	iv := newRunInterval(time.Now(), c.start, c.config.TPS, c.nodeCount(), r)
+	if c.ts != nil {
+		if err := c.ts.write(iv); err != nil {
+			c.lgr.Warn("Failed to write timeseries file", zap.Error(err))
+		}
+	}
+	if c.results != nil {
+		c.results.Intervals = append(c.results.Intervals, iv)
+	}
+	return r
+}
+
+// nodeCount returns the number of urls, up to the last one dialed.
+func (c *Chainload) nodeCount() int {
+	return c.nodes[len(c.nodes)-1].Number + 1
+}
+
+// writeResults writes the results of the run, which ended at end, to the -out
+// file, summarizing the load.
+func (c *Chainload) writeResults(load *Report, end time.Time) {
+	c.results.End = end.UTC()
+	c.results.Summary = newRunInterval(end, c.start, c.config.TPS, c.nodeCount(), load)
+	if err := c.results.write(c.config.Out); err != nil {
+		c.lgr.Warn("Failed to write results", zap.Error(err))
+		return
+	}
+	c.lgr.Info("Wrote results", zap.String("file", c.config.Out))
+}
+
+// needContract returns true if the test contract is used.
+func (c *Chainload) needContract() bool {
+	if c.config.ContractTxs > 0 {
+		return true
+	}
+	for _, s := range c.wsSubs {
+		if s == subLogs {
+			return true
+		}
+	}
+	if c.config.Reads == 0 {
+		return false
+	}
+	for _, m := range c.readMethods {
+		if m == readCall || m == readLogs {
+			return true
+		}
+	}
+	return false
+}
+
+// startReaders starts one Reader per request per second, and schedules their
+// requests in 1/10 second batches.
+func (c *Chainload) startReaders(ctx context.Context, wg *sync.WaitGroup) {
+	reqs := make(chan struct{}, c.config.Reads)
+	for num := 0; num < c.config.Reads; num++ {
+		r := &Reader{
+			Node:      c.nodes[num%len(c.nodes)],
+			Number:    num,
+			methods:   c.readMethods,
+			contract:  c.contract,
+			logsRange: c.config.LogsRange,
+			rng:       newRand(c.rng),
+		}
+		wg.Add(1)
+		go r.Read(ctx, reqs, wg.Done)
+	}
+	c.lgr.Info("Started readers", zap.Int("count", c.config.Reads), zap.Strings("methods", c.readMethods))
+
+	go func() {
+		defer close(reqs)
+		const batchCount = 10
+		batch := time.NewTicker(time.Second / batchCount)
+		defer batch.Stop()
+		batches := make([]int, batchCount)
+		distribute(c.config.Reads, batches)
+		for cnt := 0; ; cnt++ {
+			select {
+			case <-ctx.Done():
+				return
+			case <-batch.C:
+				for i := 0; i < batches[cnt%len(batches)]; i++ {
+					select {
+					case reqs <- struct{}{}:
+					default:
+						readSkippedMeter.Mark(1)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// startSubscribers starts WsConns subscribers for each WebSocket url.
+func (c *Chainload) startSubscribers(ctx context.Context, wg *sync.WaitGroup) {
+	for i, url := range c.wsURLs {
+		for num := 0; num < c.config.WsConns; num++ {
+			s := &Subscriber{
+				rng:      newRand(c.rng),
+				lgr:      c.lgr.With(zap.String("wsUrl", url), zap.Int("subscriber", num)),
+				URL:      url,
+				Number:   i*c.config.WsConns + num,
+				subs:     c.wsSubs,
+				contract: c.contract,
+			}
+			wg.Add(1)
+			go s.Run(ctx, wg.Done)
+		}
+	}
+	c.lgr.Info("Started subscribers", zap.Int("count", len(c.wsURLs)*c.config.WsConns), zap.Strings("subscriptions", c.wsSubs))
+}
+
+// reclaimSummary describes the funds recovered from senders during shutdown.
+type reclaimSummary struct {
+	dur      time.Duration
+	accounts int      // Sender accounts attempted.
+	refunded int      // Accounts which refunded a balance.
+	failed   int      // Accounts which failed to refund.
+	amount   *big.Int // Total refunded.
+}
+
+func (r *reclaimSummary) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	oe.AddDuration("duration", r.dur)
+	oe.AddInt("accounts", r.accounts)
+	oe.AddInt("refunded", r.refunded)
+	oe.AddInt("failed", r.failed)
+	addBigField(oe, "amount", r.amount)
 	return nil
 }
 
-func waitBlocks(ctx context.Context, lgr *zap.Logger, client *goclient.Client, blocks uint64) (uint64, error) {
+// reclaim refunds the balances of all sender accounts to seeders, bounded by
+// the configured timeout.
+func (c *Chainload) reclaim(senders []*Sender) reclaimSummary {
+	start := time.Now()
+	c.lgr.Info("Reclaiming sender funds", zap.Duration("timeout", c.config.Reclaim))
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.Reclaim)
+	defer cancel()
+
+	var mu sync.Mutex
+	sum := reclaimSummary{amount: new(big.Int)}
+	var wg sync.WaitGroup
+	for _, s := range senders {
+		if s.acct == nil {
+			continue
+		}
+		sum.accounts++
+		wg.Add(1)
+		go func(s *Sender) {
+			defer wg.Done()
+			amount, err := s.reclaim(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				sum.failed++
+			} else if amount != nil {
+				sum.refunded++
+				sum.amount.Add(sum.amount, amount)
+			}
+		}(s)
+	}
+	wg.Wait()
+	sum.dur = time.Since(start)
+	return sum
+}
+
+func waitBlocks(ctx context.Context, lgr *zap.Logger, client Client, blocks uint64) (uint64, error) {
 	var first *uint64
 	for {
 		t := time.Now()
@@ -266,3 +953,13 @@ func waitBlocks(ctx context.Context, lgr *zap.Logger, client *goclient.Client, b
 		}
 	}
 }
+
+// writeJUnit writes the stages and SLO checks of the run, which returned err.
+func (c *Chainload) writeJUnit(err error) {
+	j := newJUnit(configFields(c.config), c.start, time.Now(), c.stages, c.checks, c.load, err)
+	if err := j.write(c.config.JUnit); err != nil {
+		c.lgr.Warn("Failed to write junit file", zap.Error(err))
+		return
+	}
+	c.lgr.Info("Wrote junit file", zap.String("file", c.config.JUnit))
+}
@@ -0,0 +1,179 @@
+package chainload
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gochain-io/chainload/mocknode"
+	"github.com/gochain/gochain/v3/accounts"
+	"github.com/gochain/gochain/v3/accounts/keystore"
+	"github.com/gochain/gochain/v3/common"
+	"github.com/gochain/gochain/v3/goclient"
+	"go.uber.org/zap"
+)
+
+const testChainID = 1234
+
+// newTestNode returns a mock node and a Node connected to it, with an
+// AccountStore over a new plaintext keystore. Each of funds creates a
+// keystore account with that balance, in order. The returned func cleans up.
+func newTestNode(t *testing.T, funds ...int64) (*mocknode.Node, *Node, []accounts.Account, func()) {
+	dir, err := ioutil.TempDir("", "chainload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := keystore.NewPlaintextKeyStore(filepath.Join(dir, "keystore"))
+	alloc := make(map[common.Address]*big.Int)
+	var accts []accounts.Account
+	for _, f := range funds {
+		acct, err := ks.NewAccount("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		alloc[acct.Address] = big.NewInt(f)
+		accts = append(accts, acct)
+	}
+	as, err := NewAccountStore(ks, big.NewInt(testChainID), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs, err := newErrClassifier("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := mocknode.New(mocknode.Config{BlockTime: 50 * time.Millisecond, Alloc: alloc})
+	client, err := goclient.Dial(mock.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := &Node{
+		lgr:          zap.NewNop(),
+		gas:          21000,
+		Client:       client,
+		AccountStore: as,
+		SeedCh:       make(chan SeedReq),
+		errs:         errs,
+	}
+	return mock, node, accts, func() {
+		mock.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestChainload_RunDryRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("end-to-end")
+	}
+	dir, err := ioutil.TempDir("", "chainload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ksDir := filepath.Join(dir, "keystore")
+	config := &Config{
+		Id:       testChainID,
+		UrlsCSV:  "http://localhost:1",
+		TPS:      100,
+		Senders:  4,
+		Cycle:    time.Hour,
+		Duration: 4 * time.Second,
+		Gas:      21000,
+		Amount:   1,
+		Keystore: ksDir,
+		Scrypt:   "none",
+		Reclaim:  time.Second,
+		Seed:     1,
+		DryRun:   true,
+
+		ReportInterval: time.Second,
+		// Start-up is too slow for the target, but is not checked.
+		SLO: SLO{MinTPS: 0.9, Intervals: true},
+	}
+	txs := sendTxTimer.Count()
+	c, err := config.NewChainload(zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if sent := sendTxTimer.Count() - txs; sent < 100 {
+		t.Errorf("expected at least 100 txs but sent %d", sent)
+	}
+	if _, err := os.Stat(statePath(ksDir)); !os.IsNotExist(err) {
+		t.Errorf("expected no state to be saved: %v", err)
+	}
+}
+
+func TestChainload_Run(t *testing.T) {
+	if testing.Short() {
+		t.Skip("end-to-end")
+	}
+	dir, err := ioutil.TempDir("", "chainload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ksDir := filepath.Join(dir, "keystore")
+	seed, err := keystore.NewPlaintextKeyStore(ksDir).NewAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := mocknode.New(mocknode.Config{
+		BlockTime: 50 * time.Millisecond,
+		Alloc:     map[common.Address]*big.Int{seed.Address: big.NewInt(1e18)},
+	})
+	defer mock.Close()
+	go func() {
+		// Once both senders are seeded, inject errors which Sender.send
+		// handles without abandoning the account.
+		for i := 0; i < 100 && mock.Nonce(seed.Address) < 2; i++ {
+			time.Sleep(100 * time.Millisecond)
+		}
+		mock.Inject(mocknode.Fault{Method: "eth_sendRawTransaction", Count: 2, Message: mocknode.ErrorMessages["nonceTooLow"]})
+		mock.Inject(mocknode.Fault{Method: "eth_sendRawTransaction", Count: 1, Message: mocknode.ErrorMessages["replaceUnderpriced"]})
+	}()
+
+	config := &Config{
+		Id:         testChainID,
+		UrlsCSV:    mock.URL,
+		TPS:        10,
+		Senders:    2,
+		Cycle:      time.Hour,
+		Duration:   8 * time.Second,
+		Gas:        21000,
+		Amount:     1,
+		Keystore:   ksDir,
+		Scrypt:     "none",
+		Receipts:   1,
+		Reclaim:    5 * time.Second,
+		NonceCheck: time.Second,
+		PriceBump:  10,
+		Seed:       1,
+	}
+	txs := sendTxTimer.Count()
+	c, err := config.NewChainload(zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if sent := sendTxTimer.Count() - txs; sent < 10 {
+		t.Errorf("expected at least 10 txs but sent %d", sent)
+	}
+	if n := mock.Nonce(seed.Address); n < 2 {
+		t.Errorf("expected seeder to seed both senders but nonce is %d", n)
+	}
+	if _, err := os.Stat(statePath(ksDir)); err != nil {
+		t.Errorf("expected state to be saved: %v", err)
+	}
+	mock.Mine()
+	if bal := mock.Balance(seed.Address); bal.Cmp(big.NewInt(1e18-1e15)) < 0 {
+		t.Errorf("expected funds to be reclaimed but seeder balance is %s", bal)
+	}
+}
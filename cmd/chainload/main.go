@@ -3,10 +3,13 @@ package main
 import (
 	"flag"
 	"fmt"
+	"math/big"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/blendle/zapdriver"
@@ -39,6 +42,48 @@ func init() {
 	flag.Uint64Var(&config.Amount, "amount", 10, "tx Amount (approximate)")
 	flag.StringVar(&config.PprofAddr, "pprof", ":6060", "pprof addr")
 	flag.DurationVar(&config.Variable, "variable", 30*time.Second, "Variable transaction rate")
+	flag.StringVar(&config.Keystore, "keystore", "keystore", "keystore directory")
+	flag.StringVar(&config.Scrypt, "scrypt", "none", "keystore encryption: none (plaintext), light or standard")
+	flag.IntVar(&config.Unlockers, "unlockers", runtime.NumCPU(), "concurrent account unlocks at start up, for encrypted keystores")
+	flag.Var(bigValue{&config.SeedMax}, "seed-max", "maximum wei per seed request - defaults to 1000x max gas fee")
+	flag.Var(bigValue{&config.Budget}, "budget", "maximum wei to spend on gas before stopping - omit for unlimited")
+	flag.DurationVar(&config.Reclaim, "reclaim", time.Minute, "timeout for refunding sender balances to seeders on exit - 0 to disable")
+	flag.StringVar(&config.Errors, "errors", "", "JSON file of additional error message patterns by class, e.g. {\"nonceTooLow\": [\"stale nonce\"]}")
+	flag.DurationVar(&config.NonceCheck, "nonce-check", 15*time.Second, "how often senders check for nonce gaps and stuck txs - 0 to disable")
+	flag.DurationVar(&config.Stuck, "stuck", time.Minute, "replace a sender's lowest pending tx after its confirmed nonce stops advancing for this long - 0 to disable")
+	flag.Uint64Var(&config.PriceBump, "price-bump", 10, "minimum gas price increase of replacement txs, in percent")
+	flag.Float64Var(&config.Breaker, "breaker", 0.5, "node error rate which opens its circuit breaker - 0 to disable")
+	flag.IntVar(&config.BreakerWindow, "breaker-window", 50, "calls over which a node's error rate is measured")
+	flag.DurationVar(&config.BreakerCooldown, "breaker-cooldown", 10*time.Second, "how long an open circuit breaker fails fast before probing the node")
+	flag.IntVar(&config.Reads, "reads", 0, "read-only requests per second, alongside transactions - 0 to disable")
+	flag.StringVar(&config.ReadMethods, "read-methods", "balance,block,blockTxs,receipt,call,logs", "csv of read methods")
+	flag.Uint64Var(&config.LogsRange, "logs-range", 100, "blocks per eth_getLogs read")
+	flag.StringVar(&config.Contract, "contract", "", "address of a previously deployed test contract - deployed if omitted and needed")
+	flag.StringVar(&config.WsUrlsCSV, "ws-urls", "", "csv of WebSocket urls to hold subscriptions against - omit to disable")
+	flag.IntVar(&config.WsConns, "ws-conns", 1, "WebSocket connections per ws url")
+	flag.StringVar(&config.WsSubs, "ws-subs", "newHeads,logs,newPendingTransactions", "csv of subscription types per WebSocket connection")
+	flag.Float64Var(&config.ContractTxs, "contract-txs", 0, "fraction of sender txs which call the test contract, emitting logs")
+	flag.Float64Var(&config.Invalid, "invalid", 0, "fraction of sender txs which are deliberately invalid")
+	flag.StringVar(&config.InvalidKinds, "invalid-kinds", "chainID,signature,gasLimit,futureNonce,oversized,duplicate", "csv of invalid tx kinds")
+	flag.IntVar(&config.DataMin, "data-min", 0, "minimum random calldata bytes per sender tx")
+	flag.IntVar(&config.DataMax, "data-max", 0, "maximum random calldata bytes per sender tx - 0 for none")
+	flag.StringVar(&config.DataDist, "data-dist", "uniform", "calldata size distribution: uniform, or exp (exponential, mean a quarter of the range)")
+	flag.Int64Var(&config.Seed, "seed", 0, "seed for all randomness, for reproducible runs - derived from the time and logged if omitted")
+	flag.BoolVar(&config.DryRun, "dry-run", false, "build and sign txs, but never send them - measures chainload's own ceiling")
+	flag.StringVar(&config.Record, "record", "", "file to record every sent tx to, for chainload replay - omit to disable")
+	flag.DurationVar(&config.ReportInterval, "report-interval", 30*time.Second, "how often to log a status report")
+	flag.StringVar(&config.Timeseries, "timeseries", "", "file to append every interval report to - CSV if it ends in .csv, otherwise JSON lines")
+	flag.StringVar(&config.Out, "out", "", "file to write the results of the run to as JSON, for chainload report and compare - omit to disable")
+	flag.StringVar(&config.JUnit, "junit", "", "file to write run stages and -slo-* checks to as JUnit XML, for CI - omit to disable")
+	flag.BoolVar(&config.TUI, "tui", false, "draw a live dashboard in the terminal, and write logs to -tui-log instead")
+	flag.StringVar(&config.TUILog, "tui-log", "chainload.log", "file to write logs to with -tui")
+	flag.Float64Var(&config.SLO.MinTPS, "slo-min-tps", 0, "fail the run if its achieved TPS is below this fraction of -tps - 0 to disable")
+	flag.Float64Var(&config.SLO.MaxErrorRate, "slo-max-error-rate", 0, "fail the run if more than this fraction of sends fail - 0 to disable")
+	flag.DurationVar(&config.SLO.MaxP99, "slo-max-p99", 0, "fail the run if the p99 send latency exceeds this - 0 to disable")
+	flag.DurationVar(&config.SLO.MaxInclusion, "slo-max-inclusion", 0, "fail the run if the p99 inclusion latency exceeds this - 0 to disable")
+	flag.BoolVar(&config.SLO.NoDropped, "slo-no-dropped", false, "fail the run if any sent tx is never confirmed")
+	flag.BoolVar(&config.SLO.Intervals, "slo-intervals", false, "also apply the -slo-* checks to every interval report")
+	flag.IntVar(&config.Receipts, "receipts", 0, "receipt polling goroutines per node, for gas accounting - 0 to estimate from gas limits")
 
 	humanLogs := flag.Bool("human", true, "Human readable logs")
 	flag.Parse()
@@ -47,6 +92,30 @@ func init() {
 	} else {
 		logCfg = zapdriver.NewProductionConfig()
 	}
+	if config.TUI && flag.NArg() == 0 {
+		// The dashboard owns the terminal.
+		logCfg.OutputPaths = []string{config.TUILog}
+		logCfg.ErrorOutputPaths = []string{config.TUILog}
+	}
+}
+
+// bigValue is a flag.Value for a decimal *big.Int.
+type bigValue struct{ b **big.Int }
+
+func (v bigValue) String() string {
+	if v.b == nil || *v.b == nil {
+		return ""
+	}
+	return (*v.b).String()
+}
+
+func (v bigValue) Set(s string) error {
+	b, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return fmt.Errorf("invalid integer: %q", s)
+	}
+	*v.b = b
+	return nil
 }
 
 func main() {
@@ -67,11 +136,27 @@ func main() {
 			fmt.Fprintln(os.Stdout, "chainload version:", version)
 			os.Exit(0)
 		}
+		switch args[0] {
+		case "replay":
+			replay(lgr, start, args[1:])
+			return
+		case "generate":
+			generate(lgr, start, args[1:])
+			return
+		case "report":
+			report(lgr, args[1:])
+			return
+		case "compare":
+			compare(lgr, args[1:])
+			return
+		}
 		lgr.Fatal("Illegal extra arguments", zap.Strings("args", flag.Args()))
 	}
 
+	config.Version = version
 	cl, err := config.NewChainload(lgr)
 	if err != nil {
+		tuiFatal(err)
 		lgr.Fatal("Failed to create Chainload", zap.Error(err))
 	}
 
@@ -92,7 +177,111 @@ func main() {
 	lgr.Info("Starting", zap.String("version", version), zap.Object("config", &config))
 	err = cl.Run()
 	if err != nil {
+		tuiFatal(err)
+		lgr.Fatal("Fatal error", zap.Error(err), zap.Duration("runtime", time.Since(start)))
+	}
+	lgr.Info("Stopped", zap.Duration("runtime", time.Since(start)))
+}
+
+// tuiFatal reports err on stderr with -tui, since logs go to a file.
+func tuiFatal(err error) {
+	if config.TUI {
+		fmt.Fprintf(os.Stderr, "Fatal error: %v - see %s\n", err, config.TUILog)
+	}
+}
+
+// replay runs the replay command: chainload [flags] replay [-speed x] [-tps n] [-workers n] file
+func replay(lgr *zap.Logger, start time.Time, args []string) {
+	rc := chainload.ReplayConfig{Id: config.Id, UrlsCSV: config.UrlsCSV, Errors: config.Errors, ReportInterval: config.ReportInterval}
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	fs.Float64Var(&rc.Speed, "speed", 1, "timing scale: 2 replays twice as fast - 0 for as fast as possible")
+	fs.IntVar(&rc.TPS, "tps", 0, "fixed send rate, ignoring recorded timing - 0 to follow -speed")
+	fs.IntVar(&rc.Workers, "workers", 100, "concurrent senders - txs from the same account are always sent in order")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		lgr.Fatal("Usage: chainload [flags] replay [-speed x] [-tps n] [-workers n] file", zap.Strings("args", fs.Args()))
+	}
+	rc.File = fs.Arg(0)
+
+	r, err := rc.NewReplayer(lgr)
+	if err != nil {
+		lgr.Fatal("Failed to create Replayer", zap.Error(err))
+	}
+	lgr.Info("Starting replay", zap.String("version", version), zap.Object("config", &rc))
+	if err := r.Run(); err != nil {
+		lgr.Fatal("Fatal error", zap.Error(err), zap.Duration("runtime", time.Since(start)))
+	}
+	lgr.Info("Stopped", zap.Duration("runtime", time.Since(start)))
+}
+
+// generate runs the generate command: chainload [flags] generate [-count n] [-gas-price wei] [-nodes n] [-workers n] [-nonces file] file
+func generate(lgr *zap.Logger, start time.Time, args []string) {
+	gc := chainload.GenerateConfig{
+		Id:        config.Id,
+		TPS:       config.TPS,
+		Senders:   config.Senders,
+		Gas:       config.Gas,
+		Amount:    config.Amount,
+		Password:  config.Password,
+		Keystore:  config.Keystore,
+		Scrypt:    config.Scrypt,
+		Unlockers: config.Unlockers,
+		Seed:      config.Seed,
+		DataMin:   config.DataMin,
+		DataMax:   config.DataMax,
+		DataDist:  config.DataDist,
+	}
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	fs.IntVar(&gc.Count, "count", 1000000, "transactions to generate")
+	fs.Uint64Var(&gc.GasPrice, "gas-price", 2000000000, "base gas price in wei - half of the txs are randomly priced up to 2x")
+	fs.IntVar(&gc.Nodes, "nodes", 1, "node indexes to spread senders over, mapped onto the replay urls")
+	fs.IntVar(&gc.Workers, "workers", runtime.NumCPU(), "concurrent signing goroutines")
+	fs.StringVar(&gc.Nonces, "nonces", "", `JSON file of starting nonces by sender address, e.g. {"0x...": 12} - defaults to the state file`)
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		lgr.Fatal("Usage: chainload [flags] generate [-count n] [-gas-price wei] [-nodes n] [-workers n] [-nonces file] file", zap.Strings("args", fs.Args()))
+	}
+	gc.File = fs.Arg(0)
+
+	lgr.Info("Starting generate", zap.String("version", version), zap.Object("config", &gc))
+	if err := gc.Generate(lgr); err != nil {
 		lgr.Fatal("Fatal error", zap.Error(err), zap.Duration("runtime", time.Since(start)))
 	}
 	lgr.Info("Stopped", zap.Duration("runtime", time.Since(start)))
 }
+
+// report runs the report command: chainload report [-o file.html] results.json
+func report(lgr *zap.Logger, args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	out := fs.String("o", "", "html output file - defaults to the results file with an .html extension")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		lgr.Fatal("Usage: chainload report [-o file.html] results.json", zap.Strings("args", fs.Args()))
+	}
+	in := fs.Arg(0)
+	if *out == "" {
+		*out = strings.TrimSuffix(in, filepath.Ext(in)) + ".html"
+	}
+	if err := chainload.RenderReport(in, *out); err != nil {
+		lgr.Fatal("Failed to render report", zap.Error(err))
+	}
+	lgr.Info("Wrote report", zap.String("file", *out))
+}
+
+// compare runs the compare command: chainload compare [-tolerance x] [-inclusion] baseline.json candidate.json
+func compare(lgr *zap.Logger, args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	tolerance := fs.Float64("tolerance", 0.1, "fraction of the baseline by which a metric may get worse before it is a regression")
+	inclusion := fs.Bool("inclusion", false, "also compare confirmed tps and inclusion latency, which are only as precise as receipt polling")
+	_ = fs.Parse(args)
+	if fs.NArg() != 2 {
+		lgr.Fatal("Usage: chainload compare [-tolerance x] [-inclusion] baseline.json candidate.json", zap.Strings("args", fs.Args()))
+	}
+	n, err := chainload.Compare(os.Stdout, fs.Arg(0), fs.Arg(1), *tolerance, *inclusion)
+	if err != nil {
+		lgr.Fatal("Failed to compare", zap.Error(err))
+	}
+	if n > 0 {
+		lgr.Fatal("Regressions detected", zap.Int("regressions", n), zap.Float64("tolerance", *tolerance))
+	}
+}
@@ -0,0 +1,149 @@
+package chainload
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+)
+
+// compareMetric is a summary statistic compared between runs.
+type compareMetric struct {
+	name   string
+	higher bool    // Whether higher values are better.
+	slack  float64 // Absolute change always tolerated, for values near zero.
+	// inclusion is set for metrics measured by polling receipts, which are only
+	// as precise as the polling and are left out unless asked for.
+	inclusion bool
+	// value returns the statistic, or false if the run has no data for it.
+	value func(*runInterval) (float64, bool)
+}
+
+var compareMetrics = []compareMetric{
+	{name: "tps", higher: true, value: func(s *runInterval) (float64, bool) { return s.TPS, true }},
+	{name: "confirmedTps", higher: true, inclusion: true, value: func(s *runInterval) (float64, bool) {
+		return s.ConfirmedTPS, s.Confirmed > 0
+	}},
+	{name: "errorRate", slack: 0.001, value: func(s *runInterval) (float64, bool) {
+		return rate(s.Errs, s.Txs+s.Errs)
+	}},
+	{name: "readErrorRate", slack: 0.001, value: func(s *runInterval) (float64, bool) {
+		return rate(s.ReadErrs, s.Reads)
+	}},
+	latencyMetric(histSendTx, "p50"),
+	latencyMetric(histSendTx, "p90"),
+	latencyMetric(histSendTx, "p99"),
+	latencyMetric(histSendTx, "p99.9"),
+	latencyMetric(histScheduledSend, "p99"),
+	inclusionMetric(latencyMetric(histInclusion, "p50")),
+	inclusionMetric(latencyMetric(histInclusion, "p99")),
+}
+
+func rate(n, total int64) (float64, bool) {
+	if total == 0 {
+		return 0, false
+	}
+	return float64(n) / float64(total), true
+}
+
+func inclusionMetric(m compareMetric) compareMetric {
+	m.inclusion = true
+	return m
+}
+
+// latencyMetric compares percentile p of method, in milliseconds.
+func latencyMetric(method, p string) compareMetric {
+	return compareMetric{name: method + "/" + p, slack: 1, value: func(s *runInterval) (float64, bool) {
+		l := s.Latency[method]
+		if l == nil || l.Count == 0 {
+			return 0, false
+		}
+		switch p {
+		case "p50":
+			return l.P50, true
+		case "p90":
+			return l.P90, true
+		case "p99":
+			return l.P99, true
+		default:
+			return l.P999, true
+		}
+	}}
+}
+
+// compareResult is the comparison of a metric between runs.
+type compareResult struct {
+	metric              string
+	baseline, candidate float64
+	ok                  bool // Whether both runs have data.
+	change              float64
+	regression          bool
+}
+
+// compare compares each metric of candidate to baseline. A metric regresses
+// when it is worse by more than tolerance, as a fraction of the baseline, and
+// by more than its slack. Inclusion metrics are only compared if inclusion is
+// set.
+func compare(baseline, candidate *runInterval, tolerance float64, inclusion bool) []compareResult {
+	var rs []compareResult
+	for _, m := range compareMetrics {
+		if m.inclusion && !inclusion {
+			continue
+		}
+		b, bok := m.value(baseline)
+		c, cok := m.value(candidate)
+		r := compareResult{metric: m.name, baseline: b, candidate: c, ok: bok && cok}
+		if r.ok {
+			if b != 0 {
+				r.change = (c - b) / b
+			} else if c != 0 {
+				r.change = math.Inf(1)
+			}
+			worse := c - b
+			if m.higher {
+				worse = b - c
+			}
+			r.regression = worse > math.Abs(b)*tolerance && worse > m.slack
+		}
+		rs = append(rs, r)
+	}
+	return rs
+}
+
+// Compare compares the summary of the candidate run results to the baseline,
+// prints a table to w, and returns the number of regressions beyond tolerance.
+// Confirmed throughput and inclusion latency are only compared if inclusion is
+// set.
+func Compare(w io.Writer, baselinePath, candidatePath string, tolerance float64, inclusion bool) (int, error) {
+	baseline, err := readRunResults(baselinePath)
+	if err != nil {
+		return 0, err
+	}
+	candidate, err := readRunResults(candidatePath)
+	if err != nil {
+		return 0, err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "metric\tbaseline\tcandidate\tchange\tstatus\t\n")
+	var regressions int
+	for _, r := range compare(baseline.Summary, candidate.Summary, tolerance, inclusion) {
+		if !r.ok {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t-\tn/a\t\n", r.metric, formatCompare(r.baseline), formatCompare(r.candidate))
+			continue
+		}
+		status := "ok"
+		if r.regression {
+			status = "REGRESSION"
+			regressions++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%+.1f%%\t%s\t\n", r.metric, formatCompare(r.baseline), formatCompare(r.candidate), 100*r.change, status)
+	}
+	if err := tw.Flush(); err != nil {
+		return 0, err
+	}
+	return regressions, nil
+}
+
+func formatCompare(v float64) string {
+	return fmt.Sprintf("%.4g", v)
+}
@@ -0,0 +1,66 @@
+package chainload
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	baseline := &runInterval{TPS: 1000, Txs: 1000, Errs: 0, Latency: map[string]*latencyStats{
+		histSendTx: {Count: 1000, P50: 10, P90: 20, P99: 50, P999: 100},
+	}}
+	for _, test := range []struct {
+		name      string
+		candidate *runInterval
+		exp       []string // Regressed metrics.
+	}{
+		{"same", baseline, nil},
+		{"within tolerance", &runInterval{TPS: 950, Txs: 950, Latency: map[string]*latencyStats{
+			histSendTx: {Count: 950, P50: 10.5, P90: 21, P99: 54, P999: 109},
+		}}, nil},
+		{"slower", &runInterval{TPS: 800, Txs: 800, Latency: map[string]*latencyStats{
+			histSendTx: {Count: 800, P50: 10, P90: 20, P99: 80, P999: 100},
+		}}, []string{"tps", histSendTx + "/p99"}},
+		{"errors", &runInterval{TPS: 1000, Txs: 1000, Errs: 10}, []string{"errorRate"}},
+		{"faster", &runInterval{TPS: 2000, Txs: 2000, Latency: map[string]*latencyStats{
+			histSendTx: {Count: 2000, P50: 1, P90: 2, P99: 5, P999: 10},
+		}}, nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, r := range compare(baseline, test.candidate, 0.1, false) {
+				if r.regression {
+					got = append(got, r.metric)
+				}
+			}
+			if len(got) != len(test.exp) {
+				t.Fatalf("expected regressions %v but got %v", test.exp, got)
+			}
+			for i := range got {
+				if got[i] != test.exp[i] {
+					t.Errorf("expected regressions %v but got %v", test.exp, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCompare_inclusion(t *testing.T) {
+	baseline := &runInterval{TPS: 1000, Confirmed: 1000, ConfirmedTPS: 1000, Latency: map[string]*latencyStats{
+		histInclusion: {Count: 1000, P50: 5000, P99: 10000},
+	}}
+	candidate := &runInterval{TPS: 1000, Confirmed: 500, ConfirmedTPS: 500, Latency: map[string]*latencyStats{
+		histInclusion: {Count: 500, P50: 10000, P99: 20000},
+	}}
+	for _, r := range compare(baseline, candidate, 0.1, false) {
+		if r.metric == "confirmedTps" || r.metric == histInclusion+"/p50" || r.metric == histInclusion+"/p99" {
+			t.Errorf("expected %s to be left out", r.metric)
+		}
+	}
+	var got int
+	for _, r := range compare(baseline, candidate, 0.1, true) {
+		if r.regression {
+			got++
+		}
+	}
+	if got != 3 {
+		t.Errorf("expected 3 regressions but got %d", got)
+	}
+}
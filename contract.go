@@ -0,0 +1,80 @@
+package chainload
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/gochain/gochain/v3/common"
+	"github.com/gochain/gochain/v3/core/types"
+	"github.com/gochain/gochain/v3/crypto"
+	"go.uber.org/zap"
+)
+
+// testContractTopic is the topic of the log emitted by every call to the test contract.
+var testContractTopic = crypto.Keccak256Hash([]byte("Chainload(bytes)"))
+
+// testContractGas is the gas limit used to deploy the test contract.
+const testContractGas = 300000
+
+// testContractCallGas is the minimum gas limit for calls to the test contract.
+const testContractCallGas = 50000
+
+// testContractRuntime returns the code of the test contract, which emits its
+// calldata in a log with testContractTopic, and returns the block number.
+func testContractRuntime() []byte {
+	code := []byte{
+		0x36, 0x60, 0x00, 0x60, 0x00, 0x37, // CALLDATACOPY(0, 0, CALLDATASIZE)
+		0x7f, // PUSH32 topic
+	}
+	code = append(code, testContractTopic.Bytes()...)
+	return append(code,
+		0x36, 0x60, 0x00, 0xa1, // LOG1(0, CALLDATASIZE, topic)
+		0x43, 0x60, 0x00, 0x52, // MSTORE(0, NUMBER)
+		0x60, 0x20, 0x60, 0x00, 0xf3, // RETURN(0, 32)
+	)
+}
+
+// testContractInit returns the creation code of the test contract.
+func testContractInit() []byte {
+	runtime := testContractRuntime()
+	n := byte(len(runtime))
+	init := []byte{
+		0x60, n, 0x60, 0x0c, 0x60, 0x00, 0x39, // CODECOPY(0, 12, len)
+		0x60, n, 0x60, 0x00, 0xf3, // RETURN(0, len)
+	}
+	return append(init, runtime...)
+}
+
+// deployTestContract deploys the test contract from the seeder's account, and
+// returns its address. It does not wait for the deployment to be mined.
+func (s *Seeder) deployTestContract(ctx context.Context) (common.Address, error) {
+	lgr := s.Node.lgr.With(seederLabel, zap.Stringer("account", s.acct.Address))
+	bo := backOff{maxWait: 30 * time.Second, wait: 1 * time.Second, lgr: lgr, rng: s.rng}
+	if !s.syncNonce(ctx, &bo, lgr) {
+		return common.Address{}, ctx.Err()
+	}
+	var gasPrice *big.Int
+	if !bo.doTimed(ctx, suggestGasPriceTimer, func() (err error) {
+		gasPrice, err = s.SuggestGasPrice(ctx)
+		if err != nil {
+			err = fmt.Errorf("failed to get gas price: %v", err)
+		}
+		return
+	}) {
+		return common.Address{}, ctx.Err()
+	}
+	tx := types.NewContractCreation(s.nonce, new(big.Int), testContractGas, gasPrice, testContractInit())
+	tx, err := s.SignTx(*s.acct, tx)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to sign deployment: %v", err)
+	}
+	if err := s.sendTx(ctx, seederRole, s.acct.Address, tx); err != nil {
+		return common.Address{}, fmt.Errorf("failed to send deployment: %v", err)
+	}
+	addr := crypto.CreateAddress(s.acct.Address, s.nonce)
+	s.nonce++
+	lgr.Info("Deployed test contract", zap.Stringer("address", addr), zap.Stringer("tx", tx.Hash()))
+	return addr, nil
+}
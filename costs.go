@@ -0,0 +1,259 @@
+package chainload
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gochain/gochain/v3"
+	"github.com/gochain/gochain/v3/common"
+	"github.com/gochain/gochain/v3/core/types"
+	metrics "github.com/rcrowley/go-metrics"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Roles of sent transactions, for accounting. Only senderRole txs are load,
+// counted towards throughput.
+const (
+	senderRole  = "sender"
+	seederRole  = "seeder"
+	refundRole  = "refund"
+	replaceRole = "replace" // Replacements of stuck sender txs.
+	fillRole    = "fill"    // No-op sender txs filling nonce gaps.
+)
+
+// roleMeter counts successful sends of role, other than senderRole.
+func roleMeter(role string) metrics.Meter {
+	return metrics.GetOrRegisterMeter("meter/sendTx/"+role, nil)
+}
+
+// roleErrMeter counts failed sends of role, other than senderRole.
+func roleErrMeter(role string) metrics.Meter {
+	return metrics.GetOrRegisterMeter("meter/sendTx/"+role+"/err", nil)
+}
+
+var (
+	transactionReceiptTimer  = metrics.GetOrRegisterTimer("timer/transactionReceipt", nil)
+	receiptsConfirmedMeter   = metrics.GetOrRegisterMeter("meter/receipts/confirmed", nil)
+	receiptsUnconfirmedMeter = metrics.GetOrRegisterMeter("meter/receipts/unconfirmed", nil)
+	receiptsSkippedMeter     = metrics.GetOrRegisterMeter("meter/receipts/skipped", nil)
+
+	spent = newCosts() // Gas spent on all sent transactions.
+)
+
+const (
+	receiptDelay   = 5 * time.Second // Wait before first polling for a receipt, and between polls.
+	receiptTimeout = 2 * time.Minute // Give up polling and keep the estimate after this long.
+	receiptQueue   = 10000           // Maximum pending receipts per poller. Overflow is skipped.
+)
+
+// costs accounts for gas spent per role. Every sent tx is first accounted
+// at its gas limit (estimated), and settled to its actual gas used once a
+// receipt is observed.
+type costs struct {
+	mu        sync.Mutex
+	estimated map[string]*big.Int
+	actual    map[string]*big.Int
+}
+
+func newCosts() *costs {
+	return &costs{
+		estimated: make(map[string]*big.Int),
+		actual:    make(map[string]*big.Int),
+	}
+}
+
+// estimate accounts for the maximum cost of tx.
+func (c *costs) estimate(role string, tx *types.Transaction) {
+	est := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas()))
+	c.mu.Lock()
+	c.estimated[role] = addBig(c.estimated[role], est)
+	c.mu.Unlock()
+}
+
+// settle replaces the estimated cost of p with the actual cost from r.
+func (c *costs) settle(p *pendingReceipt, r *types.Receipt) {
+	est := new(big.Int).Mul(p.price, new(big.Int).SetUint64(p.gas))
+	act := new(big.Int).Mul(p.price, new(big.Int).SetUint64(r.GasUsed))
+	c.mu.Lock()
+	c.estimated[p.role] = new(big.Int).Sub(addBig(c.estimated[p.role], nil), est)
+	c.actual[p.role] = addBig(c.actual[p.role], act)
+	c.mu.Unlock()
+}
+
+// Total returns the total spent, including estimates.
+func (c *costs) Total() *big.Int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	total := new(big.Int)
+	for _, v := range c.estimated {
+		total.Add(total, v)
+	}
+	for _, v := range c.actual {
+		total.Add(total, v)
+	}
+	return total
+}
+
+func (c *costs) snapshot() costSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := costSnapshot{
+		roles:     make(map[string]*big.Int),
+		total:     new(big.Int),
+		estimated: new(big.Int),
+	}
+	for role, v := range c.estimated {
+		s.roles[role] = addBig(s.roles[role], v)
+		s.estimated.Add(s.estimated, v)
+		s.total.Add(s.total, v)
+	}
+	for role, v := range c.actual {
+		s.roles[role] = addBig(s.roles[role], v)
+		s.total.Add(s.total, v)
+	}
+	return s
+}
+
+// costSnapshot holds cumulative spend at a point in time.
+type costSnapshot struct {
+	roles     map[string]*big.Int
+	total     *big.Int
+	estimated *big.Int // Portion of total not yet settled from receipts.
+}
+
+func (s *costSnapshot) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	roles := make([]string, 0, len(s.roles))
+	for role := range s.roles {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		addBigField(oe, role, s.roles[role])
+	}
+	addBigField(oe, "total", s.total)
+	addBigField(oe, "estimated", s.estimated)
+	return nil
+}
+
+// pendingReceipt is a sent tx awaiting a receipt.
+type pendingReceipt struct {
+	hash  common.Hash
+	role  string
+	price *big.Int
+	gas   uint64
+	sent  time.Time
+	next  time.Time // Next poll.
+}
+
+// watchReceipts polls for the receipts of txs sent through this node, and
+// settles their costs. Once drain is closed, it returns as soon as every tx it
+// was sent has been confirmed or timed out. Txs still pending when ctx is done
+// are counted as unconfirmed.
+func (n *Node) watchReceipts(ctx context.Context, drain <-chan struct{}, done func()) {
+	defer done()
+	var queue []*pendingReceipt
+	var draining bool
+	for {
+		if draining && len(queue) == 0 {
+			select {
+			case p := <-n.receipts:
+				queue = append(queue, p)
+			default:
+				return
+			}
+		}
+		var wait <-chan time.Time
+		if len(queue) > 0 {
+			wait = time.After(time.Until(queue[0].next))
+		}
+		select {
+		case <-ctx.Done():
+			receiptsUnconfirmedMeter.Mark(int64(len(queue)))
+			return
+		case <-drain:
+			draining, drain = true, nil
+			continue
+		case p := <-n.receipts:
+			if len(queue) >= receiptQueue {
+				receiptsSkippedMeter.Mark(1)
+				continue
+			}
+			queue = append(queue, p)
+			continue
+		case <-wait:
+		}
+		p := queue[0]
+		queue = queue[1:]
+		t := time.Now()
+		r, err := n.TransactionReceipt(ctx, p.hash)
+		if ctx.Err() != nil {
+			receiptsUnconfirmedMeter.Mark(int64(len(queue)) + 1)
+			return
+		}
+		if err == nil {
+			transactionReceiptTimer.UpdateSince(t)
+			spent.settle(p, r)
+			receiptsConfirmedMeter.Mark(1)
+			latencies.get(n.Number, histInclusion).record(t.Sub(p.sent))
+			continue
+		}
+		if err != gochain.NotFound {
+			n.lgr.Warn("Failed to get receipt", zap.Stringer("hash", p.hash), zap.Error(err))
+		}
+		if time.Since(p.sent) > receiptTimeout {
+			receiptsUnconfirmedMeter.Mark(1)
+			continue
+		}
+		p.next = time.Now().Add(receiptDelay)
+		queue = append(queue, p)
+	}
+}
+
+// sendTx sends tx from the from account, and accounts for its cost under role.
+func (n *Node) sendTx(ctx context.Context, role string, from common.Address, tx *types.Transaction) error {
+	t := time.Now()
+	err := n.SendTransaction(ctx, tx)
+	n.recordTx(ctx, t, role, from, tx, err)
+	if err != nil {
+		if err == errBreakerOpen {
+			// Not sent, so not an error of the node.
+			sendTxRejectedMeter.Mark(1)
+		} else if ctx.Err() == nil {
+			if role == senderRole {
+				sendTxErrMeter.Mark(1)
+			} else {
+				roleErrMeter(role).Mark(1)
+			}
+			n.errs.classify(err).meter().Mark(1)
+		}
+		return err
+	}
+	if role == senderRole {
+		sendTxTimer.UpdateSince(t)
+		sendTxBytesMeter.Mark(int64(tx.Size()))
+	} else {
+		roleMeter(role).Mark(1)
+	}
+	spent.estimate(role, tx)
+	recent.add(tx.Hash(), t)
+	if n.receipts != nil {
+		p := &pendingReceipt{
+			hash:  tx.Hash(),
+			role:  role,
+			price: tx.GasPrice(),
+			gas:   tx.Gas(),
+			sent:  t,
+			next:  t.Add(receiptDelay),
+		}
+		select {
+		case n.receipts <- p:
+		default:
+			receiptsSkippedMeter.Mark(1)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package chainload
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gochain/gochain/v3/common"
+	"github.com/gochain/gochain/v3/core/types"
+)
+
+func TestNode_watchReceipts(t *testing.T) {
+	mock, node, accts, cleanup := newTestNode(t, 1e18)
+	defer cleanup()
+	from := accts[0]
+	if err := node.unlock(from); err != nil {
+		t.Fatal(err)
+	}
+	node.receipts = make(chan *pendingReceipt, 10)
+	ctx := context.Background()
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		tx, err := node.SignTx(from, types.NewTransaction(nonce, common.HexToAddress("0x01"), big.NewInt(1), 21000, big.NewInt(1), nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := node.sendTx(ctx, senderRole, from.Address, tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The node drops the last tx, so it is never mined.
+	mock.Drop(from.Address, 2)
+	mock.Mine()
+	if n := mock.Nonce(from.Address); n != 2 {
+		t.Fatalf("expected nonce 2 but got %d", n)
+	}
+	// Poll now, and give up on the first miss.
+	for i := 0; i < 3; i++ {
+		p := <-node.receipts
+		p.next, p.sent = time.Now(), time.Now().Add(-receiptTimeout)
+		node.receipts <- p
+	}
+
+	watch := func(ctx context.Context) {
+		t.Helper()
+		drain := make(chan struct{})
+		close(drain)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go node.watchReceipts(ctx, drain, wg.Done)
+		wg.Wait()
+	}
+	conf, unconf := receiptsConfirmedMeter.Count(), receiptsUnconfirmedMeter.Count()
+	// Drained once every tx is found or timed out.
+	watch(ctx)
+	if n := receiptsConfirmedMeter.Count() - conf; n != 2 {
+		t.Errorf("expected 2 confirmed but got %d", n)
+	}
+	if n := receiptsUnconfirmedMeter.Count() - unconf; n != 1 {
+		t.Errorf("expected 1 unconfirmed but got %d", n)
+	}
+
+	// Txs still pending when draining times out are unconfirmed too.
+	node.receipts <- &pendingReceipt{hash: common.Hash{1}, sent: time.Now(), next: time.Now().Add(time.Hour)}
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	watch(ctx)
+	if n := receiptsUnconfirmedMeter.Count() - unconf; n != 2 {
+		t.Errorf("expected 2 unconfirmed but got %d", n)
+	}
+}
+
+func TestNode_sendTxRoles(t *testing.T) {
+	_, node, accts, cleanup := newTestNode(t, 1e18)
+	defer cleanup()
+	from := accts[0]
+	if err := node.unlock(from); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for nonce, role := range []string{senderRole, seederRole, refundRole, replaceRole, fillRole} {
+		tx, err := node.SignTx(from, types.NewTransaction(uint64(nonce), common.HexToAddress("0x01"), big.NewInt(1), 21000, big.NewInt(1), nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs, sent := sendTxTimer.Count(), roleMeter(role).Count()
+		if err := node.sendTx(ctx, role, from.Address, tx); err != nil {
+			t.Fatal(err)
+		}
+		// Only sender txs are load.
+		var wantTxs, wantSent int64 = 1, 0
+		if role != senderRole {
+			wantTxs, wantSent = 0, 1
+		}
+		if n := sendTxTimer.Count() - txs; n != wantTxs {
+			t.Errorf("%s: expected %d load txs but got %d", role, wantTxs, n)
+		}
+		if n := roleMeter(role).Count() - sent; n != wantSent {
+			t.Errorf("%s: expected %d %s txs but got %d", role, wantSent, role, n)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package chainload
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/gochain/gochain/v3/core"
+	"github.com/gochain/gochain/v3/params"
+	metrics "github.com/rcrowley/go-metrics"
+)
+
+var sendTxBytesMeter = metrics.GetOrRegisterMeter("meter/sendTx/bytes", nil)
+
+// txMaxSize is the largest encoded tx accepted by gochain's tx pool.
+const txMaxSize = 32 * 1024
+
+// txMaxEnvelope bounds the encoded size of a signed tx, besides its data.
+const txMaxEnvelope = 256
+
+// Calldata size distributions.
+const (
+	dataUniform = "uniform" // Uniform between min and max.
+	dataExp     = "exp"     // Exponential above min, with mean (max-min)/4, capped at max.
+)
+
+// dataDist generates random calldata with sizes from a distribution.
+type dataDist struct {
+	min, max int
+	dist     string
+}
+
+func newDataDist(min, max int, dist string) (*dataDist, error) {
+	if min < 0 || max < min {
+		return nil, fmt.Errorf("illegal data size range: %d-%d", min, max)
+	}
+	if max > txMaxSize-txMaxEnvelope {
+		return nil, fmt.Errorf("illegal data size %d: txs over %d bytes are rejected", max, txMaxSize)
+	}
+	switch dist {
+	case dataUniform, dataExp:
+	default:
+		return nil, fmt.Errorf("unknown data distribution %q: must be %s or %s", dist, dataUniform, dataExp)
+	}
+	return &dataDist{min: min, max: max, dist: dist}, nil
+}
+
+// size returns a random size.
+func (d *dataDist) size(rng *rand.Rand) int {
+	if d.max == d.min {
+		return d.min
+	}
+	switch d.dist {
+	case dataExp:
+		n := d.min + int(rng.ExpFloat64()*float64(d.max-d.min)/4)
+		if n > d.max {
+			n = d.max
+		}
+		return n
+	default:
+		return d.min + rng.Intn(d.max-d.min+1)
+	}
+}
+
+// maxGas returns the most gas calldata may cost on top of params.TxGas.
+func (d *dataDist) maxGas() uint64 {
+	return uint64(d.max) * params.TxDataNonZeroGas
+}
+
+// data returns random calldata, and the gas it costs on top of params.TxGas.
+func (d *dataDist) data(rng *rand.Rand) ([]byte, uint64) {
+	b := make([]byte, d.size(rng))
+	_, _ = rng.Read(b)
+	gas, err := core.IntrinsicGas(b, false, true)
+	if err != nil {
+		// Overflow - the node will reject it anyway.
+		return b, 0
+	}
+	return b, gas - params.TxGas
+}
@@ -0,0 +1,42 @@
+package chainload
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func Test_dataDist(t *testing.T) {
+	for _, test := range []struct {
+		min, max int
+		dist     string
+	}{
+		{0, 0, dataUniform},
+		{10, 10, dataExp},
+		{0, 16 * 1024, dataUniform},
+		{100, 1000, dataExp},
+	} {
+		d, err := newDataDist(test.min, test.max, test.dist)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rng := rand.New(rand.NewSource(1))
+		for i := 0; i < 100; i++ {
+			b, gas := d.data(rng)
+			if len(b) < test.min || len(b) > test.max {
+				t.Errorf("%d-%d %s: size %d out of range", test.min, test.max, test.dist, len(b))
+			}
+			if min := uint64(len(b)) * 4; gas < min {
+				t.Errorf("%d-%d %s: expected at least %d gas for %d bytes but got %d", test.min, test.max, test.dist, min, len(b), gas)
+			}
+			if gas > d.maxGas() {
+				t.Errorf("%d-%d %s: expected at most %d gas but got %d", test.min, test.max, test.dist, d.maxGas(), gas)
+			}
+		}
+	}
+	if _, err := newDataDist(10, 5, dataUniform); err == nil {
+		t.Error("expected error for max < min")
+	}
+	if _, err := newDataDist(0, txMaxSize, dataUniform); err == nil {
+		t.Error("expected error for txs over the size limit")
+	}
+}
@@ -1,16 +1,216 @@
 package chainload
 
-import "strings"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"syscall"
 
-func nonceErr(msg string) bool {
-	return msg == "nonce too low"
+	metrics "github.com/rcrowley/go-metrics"
+)
+
+// errClass is a category of RPC error, which determines how it is handled.
+type errClass string
+
+const (
+	errClassNone               errClass = ""
+	errClassNonceTooLow        errClass = "nonceTooLow"
+	errClassKnownTx            errClass = "knownTx"
+	errClassReplaceUnderpriced errClass = "replaceUnderpriced"
+	errClassUnderpriced        errClass = "underpriced"
+	errClassLowFunds           errClass = "lowFunds"
+	errClassPoolLimit          errClass = "poolLimit"
+	errClassNonceTooHigh       errClass = "nonceTooHigh"
+	errClassInvalidSender      errClass = "invalidSender"
+	errClassGasLimit           errClass = "gasLimit"
+	errClassOversized          errClass = "oversized"
+	errClassTimeout            errClass = "timeout"
+	errClassConnection         errClass = "connection"
+	errClassRateLimited        errClass = "rateLimited"
+	errClassServer             errClass = "server"
+	errClassBreakerOpen        errClass = "breakerOpen"
+	errClassOther              errClass = "other"
+)
+
+// errClasses lists every errClass of send errors, in reporting order. Sends
+// rejected by an open breaker are not errors, and are counted separately.
+var errClasses = []errClass{
+	errClassNonceTooLow,
+	errClassKnownTx,
+	errClassReplaceUnderpriced,
+	errClassUnderpriced,
+	errClassLowFunds,
+	errClassPoolLimit,
+	errClassNonceTooHigh,
+	errClassInvalidSender,
+	errClassGasLimit,
+	errClassOversized,
+	errClassTimeout,
+	errClassConnection,
+	errClassRateLimited,
+	errClassServer,
+	errClassOther,
+}
+
+func (c errClass) meter() metrics.Meter {
+	return metrics.GetOrRegisterMeter("meter/err/"+string(c), nil)
+}
+
+// transport returns true if c indicates a problem with the node or the
+// connection to it, rather than with the request.
+func (c errClass) transport() bool {
+	switch c {
+	case errClassTimeout, errClassConnection, errClassRateLimited, errClassServer:
+		return true
+	}
+	return false
+}
+
+// errPattern maps a lower case message substring to a class.
+type errPattern struct {
+	class  errClass
+	substr string
+}
+
+// defaultErrPatterns covers message variants from gochain, geth and other
+// clients. Order matters: more specific patterns must come first.
+var defaultErrPatterns = []errPattern{
+	{errClassReplaceUnderpriced, "replacement transaction underpriced"},
+	{errClassReplaceUnderpriced, "replacement fee too low"},
+	{errClassNonceTooLow, "nonce too low"},
+	{errClassNonceTooLow, "nonce is too low"},
+	{errClassNonceTooLow, "invalid nonce"},
+	{errClassNonceTooLow, "oldnonce"},
+	{errClassNonceTooLow, "nonce has already been used"},
+	{errClassKnownTx, "known transaction"},
+	{errClassKnownTx, "already known"},
+	{errClassKnownTx, "alreadyknown"},
+	{errClassKnownTx, "already imported"},
+	{errClassKnownTx, "already in mempool"},
+	{errClassUnderpriced, "transaction underpriced"},
+	{errClassUnderpriced, "gas price too low"},
+	{errClassUnderpriced, "fee too low"},
+	{errClassLowFunds, "insufficient funds"},
+	{errClassLowFunds, "insufficient balance"},
+	{errClassPoolLimit, "transaction pool limit reached"},
+	{errClassPoolLimit, "txpool is full"},
+	{errClassPoolLimit, "pool is full"},
+	{errClassNonceTooHigh, "nonce too high"},
+	{errClassNonceTooHigh, "nonce is too high"},
+	{errClassInvalidSender, "invalid sender"},
+	{errClassInvalidSender, "invalid chain id"},
+	{errClassInvalidSender, "invalid signature"},
+	{errClassInvalidSender, "invalid transaction v, r, s"},
+	{errClassGasLimit, "exceeds block gas limit"},
+	{errClassGasLimit, "gas limit reached"},
+	{errClassOversized, "oversized data"},
+	{errClassOversized, "transaction too large"},
+	{errClassRateLimited, "too many requests"},
+	{errClassRateLimited, "rate limit"},
+	{errClassRateLimited, "limit exceeded"},
+	{errClassTimeout, "timeout"},
+	{errClassTimeout, "timed out"},
+	{errClassTimeout, "deadline exceeded"},
+	{errClassConnection, "connection refused"},
+	{errClassConnection, "connection reset"},
+	{errClassConnection, "broken pipe"},
+	{errClassConnection, "no such host"},
+	{errClassServer, "server is shutting down"},
+	{errClassBreakerOpen, "circuit breaker open"},
+}
+
+// errClassifier classifies RPC errors by type, JSON-RPC error code, HTTP
+// status and message.
+type errClassifier struct {
+	patterns []errPattern
+}
+
+// newErrClassifier returns a classifier with the default patterns, preceded by
+// any from the JSON file at path, which maps class names to lists of message
+// substrings, e.g. {"nonceTooLow": ["stale nonce"]}.
+func newErrClassifier(path string) (*errClassifier, error) {
+	c := &errClassifier{}
+	if path != "" {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read error patterns: %v", err)
+		}
+		var custom map[errClass][]string
+		if err := json.Unmarshal(b, &custom); err != nil {
+			return nil, fmt.Errorf("failed to parse error patterns %s: %v", path, err)
+		}
+		for _, class := range errClasses {
+			for _, substr := range custom[class] {
+				c.patterns = append(c.patterns, errPattern{class: class, substr: strings.ToLower(substr)})
+			}
+			delete(custom, class)
+		}
+		for class := range custom {
+			return nil, fmt.Errorf("unknown error class in %s: %q", path, class)
+		}
+	}
+	c.patterns = append(c.patterns, defaultErrPatterns...)
+	return c, nil
 }
 
-func knownTxErr(msg string) bool {
-	return msg == "replacement transaction underpriced" ||
-		strings.HasPrefix(msg, "known transaction")
+// classify returns the class of err, or errClassNone if err is nil.
+func (c *errClassifier) classify(err error) errClass {
+	if err == nil {
+		return errClassNone
+	}
+	if err == errBreakerOpen {
+		return errClassBreakerOpen
+	}
+	msg := strings.ToLower(err.Error())
+	for _, p := range c.patterns {
+		if strings.Contains(msg, p.substr) {
+			return p.class
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errClassTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return errClassTimeout
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return errClassConnection
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return errClassConnection
+	}
+	// Non-2xx HTTP responses are reported as the status, e.g. "503 Service Unavailable".
+	if len(msg) >= 4 && msg[3] == ' ' {
+		switch {
+		case msg[:3] == "429":
+			return errClassRateLimited
+		case msg[0] == '5' && isDigits(msg[1:3]):
+			return errClassServer
+		}
+	}
+	var rpcErr interface{ ErrorCode() int }
+	if errors.As(err, &rpcErr) {
+		switch code := rpcErr.ErrorCode(); {
+		case code == -32005:
+			return errClassRateLimited
+		case code == -32603:
+			return errClassServer
+		}
+	}
+	return errClassOther
 }
 
-func lowFundsErr(msg string) bool {
-	return msg == "insufficient funds for gas * price + value"
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
 }
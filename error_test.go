@@ -0,0 +1,85 @@
+package chainload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+type codeErr struct {
+	code int
+	msg  string
+}
+
+func (e *codeErr) Error() string  { return e.msg }
+func (e *codeErr) ErrorCode() int { return e.code }
+
+func Test_errClassifier_classify(t *testing.T) {
+	c, err := newErrClassifier("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		err   error
+		class errClass
+	}{
+		{nil, errClassNone},
+		{errors.New("nonce too low"), errClassNonceTooLow},
+		{errors.New("Nonce is too low: next nonce 5"), errClassNonceTooLow},
+		{errors.New("known transaction: 0x1234"), errClassKnownTx},
+		{errors.New("already known"), errClassKnownTx},
+		{errors.New("replacement transaction underpriced"), errClassReplaceUnderpriced},
+		{errors.New("transaction underpriced"), errClassUnderpriced},
+		{errors.New("insufficient funds for gas * price + value"), errClassLowFunds},
+		{errors.New("transaction pool limit reached"), errClassPoolLimit},
+		{errors.New("nonce too high"), errClassNonceTooHigh},
+		{errors.New("invalid sender"), errClassInvalidSender},
+		{errors.New("exceeds block gas limit"), errClassGasLimit},
+		{errors.New("oversized data"), errClassOversized},
+		{errors.New("429 Too Many Requests"), errClassRateLimited},
+		{errors.New("503 Service Unavailable"), errClassServer},
+		{&codeErr{code: -32005, msg: "request rejected"}, errClassRateLimited},
+		{&codeErr{code: -32603, msg: "internal"}, errClassServer},
+		{&codeErr{code: -32000, msg: "nonce too low"}, errClassNonceTooLow},
+		{fmt.Errorf("post: %w", context.DeadlineExceeded), errClassTimeout},
+		{&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, errClassConnection},
+		{errBreakerOpen, errClassBreakerOpen},
+		{errors.New("something else"), errClassOther},
+	} {
+		if got := c.classify(test.err); got != test.class {
+			t.Errorf("%v: expected %q but got %q", test.err, test.class, got)
+		}
+	}
+}
+
+func Test_newErrClassifier_custom(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chainload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "errors.json")
+	if err := ioutil.WriteFile(path, []byte(`{"nonceTooLow": ["Stale Nonce"]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := newErrClassifier(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.classify(errors.New("stale nonce: 4")); got != errClassNonceTooLow {
+		t.Errorf("expected %q but got %q", errClassNonceTooLow, got)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"bogus": ["x"]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newErrClassifier(path); err == nil {
+		t.Error("expected error for unknown class")
+	}
+}
@@ -0,0 +1,289 @@
+package chainload
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"math/rand"
+	"runtime"
+	"time"
+
+	"github.com/gochain/gochain/v3/accounts"
+	"github.com/gochain/gochain/v3/common"
+	"github.com/gochain/gochain/v3/core/types"
+	"github.com/gochain/gochain/v3/rlp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// generateBatch is the number of txs signed per unit of work.
+const generateBatch = 1000
+
+// GenerateConfig configures Generate.
+type GenerateConfig struct {
+	Id        uint64
+	File      string // Record file to write.
+	Count     int    // Txs to generate.
+	TPS       int    // Rate at which the txs are timestamped, for replay.
+	Senders   int
+	Nodes     int // Node indexes to spread senders over.
+	Gas       uint64
+	Amount    uint64
+	GasPrice  uint64
+	Password  string
+	Keystore  string
+	Scrypt    string
+	Unlockers int
+	Workers   int    // Signing goroutines.
+	Nonces    string // Optional JSON file of starting nonces by sender address.
+	Seed      int64
+
+	DataMin  int
+	DataMax  int
+	DataDist string
+}
+
+func (c *GenerateConfig) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	oe.AddUint64("id", c.Id)
+	oe.AddString("file", c.File)
+	oe.AddInt("count", c.Count)
+	oe.AddInt("tps", c.TPS)
+	oe.AddInt("senders", c.Senders)
+	oe.AddInt("nodes", c.Nodes)
+	oe.AddUint64("gas", c.Gas)
+	oe.AddUint64("amount", c.Amount)
+	oe.AddUint64("gasPrice", c.GasPrice)
+	oe.AddString("keystore", c.Keystore)
+	oe.AddString("scrypt", c.Scrypt)
+	oe.AddInt("unlockers", c.Unlockers)
+	oe.AddInt("workers", c.Workers)
+	oe.AddString("nonces", c.Nonces)
+	oe.AddInt64("seed", c.Seed)
+	oe.AddInt("dataMin", c.DataMin)
+	oe.AddInt("dataMax", c.DataMax)
+	oe.AddString("dataDist", c.DataDist)
+	return nil
+}
+
+// genSender is an account which signs generated txs.
+type genSender struct {
+	acct  *accounts.Account
+	nonce uint64 // Starting nonce.
+}
+
+// Generate signs config.Count txs offline, and writes them to config.File in
+// record format, for replay. Txs are spread round-robin over the senders, with
+// consecutive nonces from each sender's nonce in config.Nonces, or else its last
+// known nonce in the state file, and timestamped at config.TPS. The state file
+// is left unchanged, since the corpus may never be sent.
+func (config *GenerateConfig) Generate(lgr *zap.Logger) error {
+	if config.Count < 1 {
+		return fmt.Errorf("illegal count argument: %d", config.Count)
+	}
+	if config.TPS < 1 {
+		return fmt.Errorf("illegal TPS argument: %d", config.TPS)
+	}
+	if config.Senders < 1 {
+		config.Senders = config.TPS
+	}
+	if config.Nodes < 1 {
+		config.Nodes = 1
+	}
+	if config.Workers < 1 {
+		config.Workers = runtime.NumCPU()
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+		lgr.Info("Derived random seed", zap.Int64("seed", config.Seed))
+	}
+	var data *dataDist
+	if config.DataMax > 0 {
+		var err error
+		data, err = newDataDist(config.DataMin, config.DataMax, config.DataDist)
+		if err != nil {
+			return err
+		}
+	}
+
+	if config.Keystore == "" {
+		config.Keystore = "keystore"
+	}
+	ks, err := openKeyStore(config.Keystore, config.Scrypt)
+	if err != nil {
+		return err
+	}
+	as, err := NewAccountStore(ks, new(big.Int).SetUint64(config.Id), config.Password, statePath(config.Keystore))
+	if err != nil {
+		return err
+	}
+	if config.Scrypt != "" && config.Scrypt != "none" {
+		lgr.Info("Unlocking accounts...", zap.Int("unlockers", config.Unlockers))
+		n := as.Unlock(context.Background(), lgr, config.Unlockers, config.Senders)
+		lgr.Info("Accounts unlocked", zap.Int("count", n))
+	}
+	var nonces map[common.Address]uint64
+	if config.Nonces != "" {
+		nonces, err = readNonces(config.Nonces)
+		if err != nil {
+			return err
+		}
+	}
+	senders := make([]genSender, config.Senders)
+	recv := make([]common.Address, config.Senders)
+	for i := range senders {
+		acct, nonce, err := as.NextAny()
+		if err != nil {
+			return err
+		}
+		if acct == nil {
+			return fmt.Errorf("not enough accounts: found %d of %d senders", i, config.Senders)
+		}
+		if n, ok := nonces[acct.Address]; ok {
+			nonce = n
+		}
+		senders[i] = genSender{acct: acct, nonce: nonce}
+		recv[i] = acct.Address
+	}
+
+	rec, err := newRecorder(config.File, config.Id)
+	if err != nil {
+		return err
+	}
+	lgr.Info("Generating txs", zap.Int("count", config.Count), zap.Int("senders", len(senders)))
+	start := time.Now()
+	g := &generator{config: config, as: as, senders: senders, recv: recv, data: data, start: start}
+	genErr := g.run(rec)
+	if err := rec.Close(); err != nil && genErr == nil {
+		genErr = fmt.Errorf("failed to write record file: %v", err)
+	}
+	if genErr != nil {
+		return genErr
+	}
+	dur := time.Since(start)
+	lgr.Info("Generated txs", zap.Int("count", config.Count), zap.Duration("duration", dur),
+		zap.Float64("perSec", float64(config.Count)/dur.Seconds()))
+	return nil
+}
+
+// readNonces reads a JSON object of nonces by address from path.
+func readNonces(path string) (map[common.Address]uint64, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nonces file: %v", err)
+	}
+	var nonces map[common.Address]uint64
+	if err := json.Unmarshal(b, &nonces); err != nil {
+		return nil, fmt.Errorf("failed to parse nonces file: %v", err)
+	}
+	return nonces, nil
+}
+
+type generator struct {
+	config  *GenerateConfig
+	as      *AccountStore
+	senders []genSender
+	recv    []common.Address
+	data    *dataDist
+	start   time.Time
+}
+
+// run signs every batch concurrently, and writes them to rec in order.
+func (g *generator) run(rec *recorder) error {
+	type result struct {
+		recs []*txRecord
+		err  error
+	}
+	type job struct {
+		batch int
+		out   chan<- result
+	}
+	batches := (g.config.Count + generateBatch - 1) / generateBatch
+	jobs := make(chan job)
+	ordered := make(chan chan result, 2*g.config.Workers)
+	done := make(chan struct{})
+	defer close(done)
+	for i := 0; i < g.config.Workers; i++ {
+		go func() {
+			for j := range jobs {
+				recs, err := g.batch(j.batch)
+				j.out <- result{recs: recs, err: err}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		defer close(ordered)
+		for b := 0; b < batches; b++ {
+			out := make(chan result, 1)
+			select {
+			case ordered <- out:
+			case <-done:
+				return
+			}
+			select {
+			case jobs <- job{batch: b, out: out}:
+			case <-done:
+				return
+			}
+		}
+	}()
+	for out := range ordered {
+		r := <-out
+		if r.err != nil {
+			return r.err
+		}
+		for _, tr := range r.recs {
+			rec.write(tr)
+		}
+	}
+	return nil
+}
+
+// batch signs the txs of batch b. Each batch draws from its own rng, seeded
+// from its index, so the output does not depend on scheduling.
+func (g *generator) batch(b int) ([]*txRecord, error) {
+	rng := rand.New(rand.NewSource(g.config.Seed + int64(b)))
+	first := b * generateBatch
+	last := first + generateBatch
+	if last > g.config.Count {
+		last = g.config.Count
+	}
+	interval := time.Second / time.Duration(g.config.TPS)
+	recs := make([]*txRecord, 0, last-first)
+	for i := first; i < last; i++ {
+		si := i % len(g.senders)
+		s := g.senders[si]
+		nonce := s.nonce + uint64(i/len(g.senders))
+		to := g.recv[rng.Intn(len(g.recv))]
+		gp := g.config.GasPrice
+		if rng.Intn(2) == 0 {
+			gp = randBetween(rng, gp, gp*2)
+		}
+		amount := new(big.Int).SetUint64(randBetween(rng, g.config.Amount, 2*g.config.Amount))
+		gas := randBetween(rng, g.config.Gas, 2*g.config.Gas)
+		var data []byte
+		if g.data != nil {
+			var dataGas uint64
+			data, dataGas = g.data.data(rng)
+			gas += dataGas
+		}
+		tx, err := g.as.SignTx(*s.acct, types.NewTransaction(nonce, to, amount, gas, new(big.Int).SetUint64(gp), data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign tx: %v", err)
+		}
+		raw, err := rlp.EncodeToBytes(tx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode tx: %v", err)
+		}
+		recs = append(recs, &txRecord{
+			time: g.start.Add(time.Duration(i) * interval),
+			node: si % g.config.Nodes,
+			role: senderRole,
+			from: s.acct.Address,
+			raw:  raw,
+		})
+	}
+	return recs, nil
+}
@@ -0,0 +1,158 @@
+package chainload
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gochain-io/chainload/mocknode"
+	"github.com/gochain/gochain/v3/accounts/keystore"
+	"github.com/gochain/gochain/v3/common"
+	"go.uber.org/zap"
+)
+
+func TestGenerateConfig_Generate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chainload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ksDir := filepath.Join(dir, "keystore")
+	ks := keystore.NewPlaintextKeyStore(ksDir)
+	alloc := make(map[common.Address]*big.Int)
+	for i := 0; i < 2; i++ {
+		acct, err := ks.NewAccount("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		alloc[acct.Address] = big.NewInt(1000000000000)
+	}
+
+	path := filepath.Join(dir, "corpus.rec")
+	config := &GenerateConfig{
+		Id:       testChainID,
+		File:     path,
+		Count:    2*generateBatch + 7,
+		TPS:      100,
+		Senders:  2,
+		Gas:      21000,
+		Amount:   10,
+		GasPrice: 1,
+		Keystore: ksDir,
+		Workers:  3,
+		Seed:     1,
+	}
+	if err := config.Generate(zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each sender's nonces must be consecutive from 0, in file order.
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rr, err := newRecordReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonces := make(map[common.Address]uint64)
+	var count int
+	for {
+		rec, err := rr.next()
+		if err != nil {
+			break
+		}
+		tx, err := rec.tx()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.Nonce() != nonces[rec.from] {
+			t.Fatalf("record %d: expected nonce %d but got %d", count, nonces[rec.from], tx.Nonce())
+		}
+		nonces[rec.from]++
+		count++
+	}
+	if count != config.Count {
+		t.Errorf("expected %d records but got %d", config.Count, count)
+	}
+
+	// The state file is unchanged, since the corpus is not sent yet.
+	if _, err := os.Stat(statePath(ksDir)); !os.IsNotExist(err) {
+		t.Errorf("expected no state to be saved: %v", err)
+	}
+
+	// A later corpus continues from the given nonces.
+	var next common.Address
+	for addr := range nonces {
+		next = addr
+		break
+	}
+	noncesPath := filepath.Join(dir, "nonces.json")
+	if err := ioutil.WriteFile(noncesPath, []byte(`{"`+next.Hex()+`": 5000}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := *config
+	later.File = filepath.Join(dir, "later.rec")
+	later.Count = 4
+	later.Nonces = noncesPath
+	if err := later.Generate(zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+	lf, err := os.Open(later.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lf.Close()
+	lr, err := newRecordReader(lf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := make(map[common.Address]uint64)
+	for {
+		rec, err := lr.next()
+		if err != nil {
+			break
+		}
+		tx, err := rec.tx()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := first[rec.from]; !ok {
+			first[rec.from] = tx.Nonce()
+		}
+	}
+	for addr := range nonces {
+		want := uint64(0)
+		if addr == next {
+			want = 5000
+		}
+		if got, ok := first[addr]; !ok || got != want {
+			t.Errorf("%s: expected first nonce %d but got %d", addr.Hex(), want, got)
+		}
+	}
+
+	if testing.Short() {
+		return
+	}
+	// Stream the corpus out at a fixed rate.
+	mock := mocknode.New(mocknode.Config{BlockTime: 50 * time.Millisecond, Alloc: alloc, PoolLimit: config.Count, GasLimit: 1e9})
+	defer mock.Close()
+	rc := &ReplayConfig{Id: testChainID, UrlsCSV: mock.URL, File: path, TPS: 2000, Workers: 4}
+	r, err := rc.NewReplayer(zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	mock.Mine()
+	for addr, n := range nonces {
+		if got := mock.Nonce(addr); got != n {
+			t.Errorf("%s: expected nonce %d but got %d", addr.Hex(), n, got)
+		}
+	}
+}
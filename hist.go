@@ -0,0 +1,439 @@
+package chainload
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gochain/gochain/v3"
+	"github.com/gochain/gochain/v3/common"
+	"github.com/gochain/gochain/v3/core/types"
+	"go.uber.org/zap/zapcore"
+)
+
+// Latency histograms are log-linear, like HDR histograms: each power of two
+// microseconds is split into 2^(histSubBits-1) linear buckets, so recorded
+// values are accurate to within 1/2^(histSubBits-1) (under 2%), up to histMax.
+const (
+	histSubBits = 7
+	histSub     = 1 << histSubBits
+	histHalf    = histSub / 2
+	histMax     = int64(time.Hour / time.Microsecond)
+)
+
+var histBuckets = histIndex(histMax) + 1
+
+// histIndex returns the bucket of v microseconds.
+func histIndex(v int64) int {
+	if v < histSub {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - histSubBits
+	sub := v >> uint(shift)
+	return histSub + (shift-1)*histHalf + int(sub-histHalf)
+}
+
+// histValue returns the highest value in bucket i, in microseconds.
+func histValue(i int) int64 {
+	if i < histSub {
+		return int64(i)
+	}
+	shift := (i-histSub)/histHalf + 1
+	sub := int64((i-histSub)%histHalf + histHalf)
+	return (sub+1)<<uint(shift) - 1
+}
+
+// histogram records a distribution of latencies. It is safe for concurrent use.
+type histogram struct {
+	mu     sync.Mutex
+	counts []int64
+	count  int64
+	max    int64 // Microseconds.
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]int64, histBuckets)}
+}
+
+func (h *histogram) record(d time.Duration) {
+	v := int64(d / time.Microsecond)
+	if v < 0 {
+		v = 0
+	} else if v > histMax {
+		v = histMax
+	}
+	h.mu.Lock()
+	h.counts[histIndex(v)]++
+	h.count++
+	if v > h.max {
+		h.max = v
+	}
+	h.mu.Unlock()
+}
+
+// snapshot returns a copy of h.
+func (h *histogram) snapshot() *histogram {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c := &histogram{counts: make([]int64, len(h.counts)), count: h.count, max: h.max}
+	copy(c.counts, h.counts)
+	return c
+}
+
+// add adds the counts of o to h. The max is the greater of the two.
+func (h *histogram) add(o *histogram) {
+	for i, n := range o.counts {
+		h.counts[i] += n
+	}
+	h.count += o.count
+	if o.max > h.max {
+		h.max = o.max
+	}
+}
+
+// sub returns the counts recorded in h since snapshot prev, which may be nil.
+// The max of the difference is approximated by its highest bucket.
+func (h *histogram) sub(prev *histogram) *histogram {
+	if prev == nil {
+		return h
+	}
+	d := &histogram{counts: make([]int64, len(h.counts)), count: h.count - prev.count}
+	for i := range h.counts {
+		d.counts[i] = h.counts[i] - prev.counts[i]
+		if d.counts[i] > 0 {
+			d.max = histValue(i)
+		}
+	}
+	if d.max > h.max {
+		d.max = h.max
+	}
+	return d
+}
+
+// quantile returns the latency at quantile q, in [0,1].
+func (h *histogram) quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(h.count)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i, n := range h.counts {
+		seen += n
+		if seen >= rank {
+			v := histValue(i)
+			if v > h.max {
+				v = h.max
+			}
+			return time.Duration(v) * time.Microsecond
+		}
+	}
+	return time.Duration(h.max) * time.Microsecond
+}
+
+func (h *histogram) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	oe.AddInt64("count", h.count)
+	oe.AddDuration("p50", h.quantile(0.5))
+	oe.AddDuration("p90", h.quantile(0.9))
+	oe.AddDuration("p99", h.quantile(0.99))
+	oe.AddDuration("p99.9", h.quantile(0.999))
+	oe.AddDuration("max", time.Duration(h.max)*time.Microsecond)
+	return nil
+}
+
+// Latency histogram names.
+const (
+	histBlockNumber    = "eth_blockNumber"
+	histGasPrice       = "eth_gasPrice"
+	histPendingBalance = "eth_getBalance/pending"
+	histPendingNonce   = "eth_getTransactionCount/pending"
+	histNonce          = "eth_getTransactionCount"
+	histSendTx         = "eth_sendRawTransaction"
+	histReceipt        = "eth_getTransactionReceipt"
+	histBalance        = "eth_getBalance"
+	histHeader         = "eth_getBlockByNumber/header"
+	histBlock          = "eth_getBlockByNumber"
+	histCall           = "eth_call"
+	histLogs           = "eth_getLogs"
+
+	// Failed sends, apart from the successful ones. See errHist.
+	histSendTxErr = "eth_sendRawTransaction/err"
+
+	// Time from when a tx was due by the fixed batch schedule until it was
+	// accepted, corrected for coordinated omission: stalls delay the following
+	// sends, rather than skipping their measurements.
+	histScheduledSend = "eth_sendRawTransaction/scheduled"
+
+	// Time from when a tx was accepted until its receipt was found, by polling
+	// every receiptDelay, so it is an upper bound within that resolution.
+	histInclusion = "inclusion"
+)
+
+// histNames lists every latency histogram name, in a fixed order.
+var histNames = []string{
+	histSendTx, histSendTxErr, histScheduledSend, histInclusion, histReceipt, histPendingNonce, histNonce, histGasPrice, histBlockNumber,
+	histPendingBalance, histBalance, histHeader, histBlock, histCall, histLogs,
+}
+
+// errHist returns the name of the histogram of failed calls of method. Only
+// that of histSendTx is in histNames, and so in results, but every one is logged.
+func errHist(method string) string {
+	return method + "/err"
+}
+
+// histKey identifies a latency histogram.
+type histKey struct {
+	node   int
+	method string
+}
+
+// latencies holds the latency histograms of every node and method.
+var latencies = &histSet{m: make(map[histKey]*histogram)}
+
+type histSet struct {
+	mu sync.RWMutex
+	m  map[histKey]*histogram
+}
+
+// get returns the histogram for method on node, creating it if necessary.
+func (s *histSet) get(node int, method string) *histogram {
+	k := histKey{node: node, method: method}
+	s.mu.RLock()
+	h := s.m[k]
+	s.mu.RUnlock()
+	if h != nil {
+		return h
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if h = s.m[k]; h == nil {
+		h = newHistogram()
+		s.m[k] = h
+	}
+	return h
+}
+
+// snapshot returns a copy of every histogram.
+func (s *histSet) snapshot() latencySnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snap := make(latencySnapshot, len(s.m))
+	for k, h := range s.m {
+		snap[k] = h.snapshot()
+	}
+	return snap
+}
+
+// latencySnapshot is a set of histograms by node and method.
+type latencySnapshot map[histKey]*histogram
+
+// sub returns the counts recorded in s since prev.
+func (s latencySnapshot) sub(prev latencySnapshot) latencySnapshot {
+	d := make(latencySnapshot, len(s))
+	for k, h := range s {
+		d[k] = h.sub(prev[k])
+	}
+	return d
+}
+
+// add returns a new sum of s and o.
+func (s latencySnapshot) add(o latencySnapshot) latencySnapshot {
+	sum := make(latencySnapshot, len(s)+len(o))
+	for _, src := range []latencySnapshot{s, o} {
+		for k, h := range src {
+			t := sum[k]
+			if t == nil {
+				t = newHistogram()
+				sum[k] = t
+			}
+			t.add(h)
+		}
+	}
+	return sum
+}
+
+// byMethod returns the histograms of each method, merged across nodes.
+func (s latencySnapshot) byMethod() latencyByName {
+	m := make(latencyByName)
+	for k, h := range s {
+		t := m[k.method]
+		if t == nil {
+			t = newHistogram()
+			m[k.method] = t
+		}
+		t.add(h)
+	}
+	return m
+}
+
+// byNode returns the histograms of each node, by method.
+func (s latencySnapshot) byNode() latencyByNode {
+	m := make(latencyByNode)
+	for k, h := range s {
+		n := m[k.node]
+		if n == nil {
+			n = make(latencyByName)
+			m[k.node] = n
+		}
+		n[k.method] = h
+	}
+	return m
+}
+
+type latencyByName map[string]*histogram
+
+func (l latencyByName) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	names := make([]string, 0, len(l))
+	for name, h := range l {
+		if h.count > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := oe.AddObject(name, l[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type latencyByNode map[int]latencyByName
+
+func (l latencyByNode) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	nodes := make([]int, 0, len(l))
+	for n := range l {
+		nodes = append(nodes, n)
+	}
+	sort.Ints(nodes)
+	for _, n := range nodes {
+		if err := oe.AddObject(fmt.Sprintf("node%d", n), l[n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// latencyClient is a Client which records the latency of calls by method, with
+// failed calls apart from successful ones (see errHist).
+type latencyClient struct {
+	Client
+	node int
+}
+
+var _ Client = (*latencyClient)(nil)
+
+func (c *latencyClient) call(ctx context.Context, method string, fn func() error) error {
+	t := time.Now()
+	err := fn()
+	switch {
+	case err == nil:
+		latencies.get(c.node, method).record(time.Since(t))
+	case err != errBreakerOpen && ctx.Err() == nil:
+		// Typically the slowest calls, e.g. timeouts, so leaving them out would
+		// flatter the tail.
+		latencies.get(c.node, errHist(method)).record(time.Since(t))
+	}
+	return err
+}
+
+func (c *latencyClient) LatestBlockNumber(ctx context.Context) (n *big.Int, err error) {
+	err = c.call(ctx, histBlockNumber, func() (err error) {
+		n, err = c.Client.LatestBlockNumber(ctx)
+		return
+	})
+	return
+}
+
+func (c *latencyClient) SuggestGasPrice(ctx context.Context) (p *big.Int, err error) {
+	err = c.call(ctx, histGasPrice, func() (err error) {
+		p, err = c.Client.SuggestGasPrice(ctx)
+		return
+	})
+	return
+}
+
+func (c *latencyClient) PendingBalanceAt(ctx context.Context, account common.Address) (b *big.Int, err error) {
+	err = c.call(ctx, histPendingBalance, func() (err error) {
+		b, err = c.Client.PendingBalanceAt(ctx, account)
+		return
+	})
+	return
+}
+
+func (c *latencyClient) PendingNonceAt(ctx context.Context, account common.Address) (n uint64, err error) {
+	err = c.call(ctx, histPendingNonce, func() (err error) {
+		n, err = c.Client.PendingNonceAt(ctx, account)
+		return
+	})
+	return
+}
+
+func (c *latencyClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (n uint64, err error) {
+	err = c.call(ctx, histNonce, func() (err error) {
+		n, err = c.Client.NonceAt(ctx, account, blockNumber)
+		return
+	})
+	return
+}
+
+func (c *latencyClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	return c.call(ctx, histSendTx, func() error {
+		return c.Client.SendTransaction(ctx, tx)
+	})
+}
+
+func (c *latencyClient) TransactionReceipt(ctx context.Context, hash common.Hash) (r *types.Receipt, err error) {
+	err = c.call(ctx, histReceipt, func() (err error) {
+		r, err = c.Client.TransactionReceipt(ctx, hash)
+		return
+	})
+	return
+}
+
+func (c *latencyClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (b *big.Int, err error) {
+	err = c.call(ctx, histBalance, func() (err error) {
+		b, err = c.Client.BalanceAt(ctx, account, blockNumber)
+		return
+	})
+	return
+}
+
+func (c *latencyClient) HeaderByNumber(ctx context.Context, number *big.Int) (h *types.Header, err error) {
+	err = c.call(ctx, histHeader, func() (err error) {
+		h, err = c.Client.HeaderByNumber(ctx, number)
+		return
+	})
+	return
+}
+
+func (c *latencyClient) BlockByNumber(ctx context.Context, number *big.Int) (b *types.Block, err error) {
+	err = c.call(ctx, histBlock, func() (err error) {
+		b, err = c.Client.BlockByNumber(ctx, number)
+		return
+	})
+	return
+}
+
+func (c *latencyClient) CallContract(ctx context.Context, msg gochain.CallMsg, blockNumber *big.Int) (out []byte, err error) {
+	err = c.call(ctx, histCall, func() (err error) {
+		out, err = c.Client.CallContract(ctx, msg, blockNumber)
+		return
+	})
+	return
+}
+
+func (c *latencyClient) FilterLogs(ctx context.Context, q gochain.FilterQuery) (logs []types.Log, err error) {
+	err = c.call(ctx, histLogs, func() (err error) {
+		logs, err = c.Client.FilterLogs(ctx, q)
+		return
+	})
+	return
+}
@@ -0,0 +1,89 @@
+package chainload
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gochain-io/chainload/mocknode"
+	"github.com/gochain/gochain/v3/common"
+)
+
+func TestHistIndex(t *testing.T) {
+	for _, v := range []int64{0, 1, histSub - 1, histSub, histSub + 1, 1000, 12345, 1 << 20, histMax} {
+		i := histIndex(v)
+		if i >= histBuckets {
+			t.Fatalf("%d: index %d out of range %d", v, i, histBuckets)
+		}
+		if hi := histValue(i); hi < v {
+			t.Errorf("%d: bucket %d max %d is too low", v, i, hi)
+		} else if float64(hi-v) > float64(v)/histHalf {
+			t.Errorf("%d: bucket %d max %d is too high", v, i, hi)
+		}
+		if i > 0 && histValue(i-1) >= v {
+			t.Errorf("%d: previous bucket %d max %d is too high", v, i-1, histValue(i-1))
+		}
+	}
+}
+
+func TestHistogram_quantile(t *testing.T) {
+	h := newHistogram()
+	for i := 1; i <= 10000; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	for _, test := range []struct {
+		q   float64
+		exp time.Duration
+	}{
+		{0, time.Millisecond},
+		{0.5, 5 * time.Second},
+		{0.9, 9 * time.Second},
+		{0.99, 9900 * time.Millisecond},
+		{0.999, 9990 * time.Millisecond},
+		{1, 10 * time.Second},
+	} {
+		got := h.quantile(test.q)
+		if got < test.exp || got > test.exp+test.exp/histHalf {
+			t.Errorf("p%v: expected %s (within %d%%) but got %s", test.q*100, test.exp, 100/histHalf, got)
+		}
+	}
+
+	prev := h.snapshot()
+	h.record(time.Minute)
+	d := h.snapshot().sub(prev)
+	if d.count != 1 {
+		t.Fatalf("expected 1 new value but got %d", d.count)
+	}
+	if got := d.quantile(0.5); got < time.Minute || got > time.Minute+time.Minute/histHalf {
+		t.Errorf("expected new p50 of %s but got %s", time.Minute, got)
+	}
+}
+
+func TestLatencyClient_call(t *testing.T) {
+	mock, node, _, cleanup := newTestNode(t)
+	defer cleanup()
+	const num = -2 // Unused by other tests.
+	c := &latencyClient{Client: node.Client, node: num}
+	ctx := context.Background()
+	addr := common.Address{1}
+	if _, err := c.PendingNonceAt(ctx, addr); err != nil {
+		t.Fatal(err)
+	}
+	mock.Inject(mocknode.Fault{Method: "eth_getTransactionCount", Count: 1, Status: 503})
+	if _, err := c.PendingNonceAt(ctx, addr); err == nil {
+		t.Fatal("expected error")
+	}
+	// Cut short by shutdown, so not recorded.
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := c.PendingNonceAt(cancelled, addr); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if n := latencies.get(num, histPendingNonce).count; n != 1 {
+		t.Errorf("expected 1 successful call but got %d", n)
+	}
+	if n := latencies.get(num, errHist(histPendingNonce)).count; n != 1 {
+		t.Errorf("expected 1 failed call but got %d", n)
+	}
+}
@@ -0,0 +1,301 @@
+package chainload
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RenderReport renders the results written by a run with -out to a single,
+// self-contained HTML file, with a summary and charts of every interval.
+func RenderReport(resultsPath, htmlPath string) error {
+	r, err := readRunResults(resultsPath)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := reportTmpl.Execute(&buf, newReportPage(r)); err != nil {
+		return fmt.Errorf("failed to render report: %v", err)
+	}
+	return ioutil.WriteFile(htmlPath, buf.Bytes(), 0644)
+}
+
+type reportPage struct {
+	*runResults
+	Charts  []template.HTML
+	Latency []reportLatency
+	Errors  []reportCount
+	Config  []reportCount
+}
+
+type reportLatency struct {
+	Method string
+	*latencyStats
+}
+
+type reportCount struct {
+	Name  string
+	Value interface{}
+}
+
+func newReportPage(r *runResults) *reportPage {
+	p := &reportPage{runResults: r}
+	for _, m := range histNames {
+		if l := r.Summary.Latency[m]; l != nil && l.Count > 0 {
+			p.Latency = append(p.Latency, reportLatency{Method: m, latencyStats: l})
+		}
+	}
+	for _, c := range errClasses {
+		if n := r.Summary.Errors[string(c)]; n > 0 {
+			p.Errors = append(p.Errors, reportCount{Name: string(c), Value: n})
+		}
+	}
+	for _, k := range sortedKeys(r.Config) {
+		p.Config = append(p.Config, reportCount{Name: k, Value: r.Config[k]})
+	}
+
+	ivs := r.Intervals
+	xs := make([]float64, len(ivs))
+	for i, iv := range ivs {
+		xs[i] = iv.Elapsed
+	}
+	series := func(name string, f func(*runInterval) float64) chartSeries {
+		s := chartSeries{name: name, ys: make([]float64, len(ivs))}
+		for i, iv := range ivs {
+			s.ys[i] = f(iv)
+		}
+		return s
+	}
+
+	tps := []chartSeries{
+		series("target", func(iv *runInterval) float64 { return float64(iv.TargetTPS) }),
+		series("sent", func(iv *runInterval) float64 { return iv.TPS }),
+	}
+	if r.Summary.Confirmed > 0 {
+		tps = append(tps, series("confirmed", func(iv *runInterval) float64 { return iv.ConfirmedTPS }))
+	}
+	p.Charts = append(p.Charts, svgLineChart("Throughput", "tx/s", xs, tps))
+
+	lat := func(m string, f func(*latencyStats) float64) func(*runInterval) float64 {
+		return func(iv *runInterval) float64 {
+			if l := iv.Latency[m]; l != nil {
+				return f(l)
+			}
+			return 0
+		}
+	}
+	p.Charts = append(p.Charts, svgLineChart("Send latency", "ms", xs, []chartSeries{
+		series("p50", lat(histSendTx, func(l *latencyStats) float64 { return l.P50 })),
+		series("p90", lat(histSendTx, func(l *latencyStats) float64 { return l.P90 })),
+		series("p99", lat(histSendTx, func(l *latencyStats) float64 { return l.P99 })),
+		series("p99.9", lat(histSendTx, func(l *latencyStats) float64 { return l.P999 })),
+		series("scheduled p99", lat(histScheduledSend, func(l *latencyStats) float64 { return l.P99 })),
+	}))
+
+	var errs []chartSeries
+	for _, c := range p.Errors {
+		class := c.Name
+		errs = append(errs, series(class, func(iv *runInterval) float64 {
+			return float64(iv.Errors[class]) / iv.Duration
+		}))
+	}
+	p.Charts = append(p.Charts, svgLineChart("Errors", "errors/s", xs, errs))
+
+	var nodes []chartSeries
+	for i := range r.Summary.Nodes {
+		i := i
+		nodes = append(nodes, series("node"+strconv.Itoa(i), func(iv *runInterval) float64 {
+			if i < len(iv.Nodes) {
+				return iv.Nodes[i].TPS
+			}
+			return 0
+		}))
+	}
+	p.Charts = append(p.Charts, svgLineChart("Node throughput", "tx/s", xs, nodes))
+
+	var states []chartSeries
+	for _, s := range stateCounters {
+		name := s.name
+		st := series(name, func(iv *runInterval) float64 { return float64(iv.States[name]) })
+		for _, y := range st.ys {
+			if y != 0 {
+				states = append(states, st)
+				break
+			}
+		}
+	}
+	p.Charts = append(p.Charts, svgLineChart("States", "goroutines", xs, states))
+	return p
+}
+
+type chartSeries struct {
+	name string
+	ys   []float64
+}
+
+var chartColors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+	"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"}
+
+// svgLineChart returns an SVG chart of each series against xs, in seconds.
+func svgLineChart(title, unit string, xs []float64, series []chartSeries) template.HTML {
+	const (
+		w, h                     = 900, 280
+		left, right, top, bottom = 70, 170, 30, 40
+		pw, ph                   = w - left - right, h - top - bottom
+	)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, w, h, w, h)
+	fmt.Fprintf(&b, `<text x="%d" y="18" class="title">%s (%s)</text>`, left, html.EscapeString(title), html.EscapeString(unit))
+	if len(xs) == 0 || len(series) == 0 {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" class="empty">No data</text></svg>`, left+pw/2, top+ph/2)
+		return template.HTML(b.String())
+	}
+
+	xmax := xs[len(xs)-1]
+	if xmax <= 0 {
+		xmax = 1
+	}
+	var ymax float64
+	for _, s := range series {
+		for _, y := range s.ys {
+			if y > ymax && !math.IsInf(y, 0) {
+				ymax = y
+			}
+		}
+	}
+	ymax = niceCeil(ymax)
+	px := func(x float64) float64 { return left + x/xmax*pw }
+	py := func(y float64) float64 { return top + ph - y/ymax*ph }
+
+	// Grid and axes.
+	const ticks = 5
+	for i := 0; i <= ticks; i++ {
+		y := ymax * float64(i) / ticks
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" class="grid"/>`, left, py(y), left+pw, py(y))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" class="ylabel">%s</text>`, left-6, py(y)+4, formatTick(y))
+		x := xmax * float64(i) / ticks
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" class="xlabel">%s</text>`, px(x), top+ph+18, formatElapsed(x))
+	}
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" class="frame"/>`, left, top, pw, ph)
+
+	for i, s := range series {
+		color := chartColors[i%len(chartColors)]
+		var d strings.Builder
+		for j, y := range s.ys {
+			if math.IsNaN(y) || math.IsInf(y, 0) {
+				y = 0
+			}
+			cmd := 'L'
+			if j == 0 {
+				cmd = 'M'
+			}
+			fmt.Fprintf(&d, "%c%.1f,%.1f ", cmd, px(xs[j]), py(y))
+		}
+		fmt.Fprintf(&b, `<path d="%s" stroke="%s" class="line"/>`, strings.TrimSpace(d.String()), color)
+		ly := top + 10 + i*18
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" class="line"/>`, left+pw+12, ly, left+pw+32, ly, color)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" class="legend">%s</text>`, left+pw+38, ly+4, html.EscapeString(s.name))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// niceCeil rounds v up to 1, 2 or 5 times a power of ten.
+func niceCeil(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	p := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if v <= m*p {
+			return m * p
+		}
+	}
+	return 10 * p
+}
+
+func formatTick(v float64) string {
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+// formatElapsed formats seconds as a short duration.
+func formatElapsed(s float64) string {
+	switch {
+	case s >= 3600:
+		return strconv.FormatFloat(s/3600, 'f', 1, 64) + "h"
+	case s >= 600:
+		return strconv.FormatFloat(s/60, 'f', 0, 64) + "m"
+	default:
+		return strconv.FormatFloat(s, 'f', 0, 64) + "s"
+	}
+}
+
+var reportTmpl = template.Must(template.New("report").Funcs(template.FuncMap{
+	"f": func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>chainload report {{.Start.Format "2006-01-02 15:04:05"}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.title { font-size: 15px; font-weight: bold; }
+.grid { stroke: #eee; }
+.frame { fill: none; stroke: #999; }
+.line { fill: none; stroke-width: 1.5; }
+.xlabel { font-size: 11px; text-anchor: middle; }
+.ylabel { font-size: 11px; text-anchor: end; }
+.legend { font-size: 12px; }
+.empty { font-size: 13px; fill: #999; text-anchor: middle; }
+details { margin-bottom: 2em; }
+</style>
+</head>
+<body>
+<h1>chainload report</h1>
+<p>{{.Start.Format "2006-01-02 15:04:05 MST"}} to {{.End.Format "2006-01-02 15:04:05 MST"}}{{if .Version}} - version {{.Version}}{{end}}</p>
+{{with .Summary}}
+<h2>Summary</h2>
+<table>
+<tr><th>Duration</th><td>{{f .Duration}}s</td></tr>
+<tr><th>Target TPS</th><td>{{.TargetTPS}}</td></tr>
+<tr><th>Sent</th><td>{{.Txs}} ({{f .TPS}}/s)</td></tr>
+<tr><th>Confirmed</th><td>{{.Confirmed}} ({{f .ConfirmedTPS}}/s)</td></tr>
+<tr><th>Errors</th><td>{{.Errs}}</td></tr>
+<tr><th>Bytes</th><td>{{.Bytes}} ({{f .BPS}}/s)</td></tr>
+<tr><th>Reads</th><td>{{.Reads}} ({{.ReadErrs}} errors)</td></tr>
+<tr><th>Spent (wei)</th><td>{{.Spent}}</td></tr>
+</table>
+{{end}}
+{{if .Latency}}
+<h2>Latency (ms)</h2>
+<table>
+<tr><th>Method</th><th>Count</th><th>p50</th><th>p90</th><th>p99</th><th>p99.9</th><th>Max</th></tr>
+{{range .Latency}}<tr><td>{{.Method}}</td><td>{{.Count}}</td><td>{{f .P50}}</td><td>{{f .P90}}</td><td>{{f .P99}}</td><td>{{f .P999}}</td><td>{{f .Max}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Errors}}
+<h2>Errors by class</h2>
+<table>
+{{range .Errors}}<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Timeline</h2>
+{{range .Charts}}<div>{{.}}</div>
+{{end}}
+<details>
+<summary>Configuration</summary>
+<table>
+{{range .Config}}<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>
+</details>
+</body>
+</html>
+`))
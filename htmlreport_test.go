@@ -0,0 +1,57 @@
+package chainload
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chainload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := time.Now()
+	res := newRunResults(&Config{Id: 1234, Password: "secret", TPS: 20}, start)
+	total := Report{errClasses: make(map[errClass]int64)}
+	for i, r := range []*Report{
+		{dur: time.Second, txs: 10},
+		{dur: 2 * time.Second, txs: 30, errs: 1, errClasses: map[errClass]int64{errClassNonceTooLow: 1}},
+	} {
+		res.Intervals = append(res.Intervals, newRunInterval(start.Add(time.Duration(i+1)*time.Second), start, 20, 2, r))
+		total.dur += r.dur
+		total.txs += r.txs
+		for c, n := range r.errClasses {
+			total.errClasses[c] += n
+		}
+	}
+	res.Summary = newRunInterval(start.Add(3*time.Second), start, 20, 2, &total)
+	res.End = start.Add(3 * time.Second)
+	jsonPath := filepath.Join(dir, "run.json")
+	if err := res.write(jsonPath); err != nil {
+		t.Fatal(err)
+	}
+
+	htmlPath := filepath.Join(dir, "run.html")
+	if err := RenderReport(jsonPath, htmlPath); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(htmlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(b)
+	for _, exp := range []string{"<svg", "Throughput", "Send latency", "Node throughput", string(errClassNonceTooLow)} {
+		if !strings.Contains(page, exp) {
+			t.Errorf("expected report to contain %q", exp)
+		}
+	}
+	if strings.Contains(page, "secret") {
+		t.Error("report contains the passphrase")
+	}
+}
@@ -0,0 +1,195 @@
+package chainload
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/gochain/gochain/v3/core/types"
+	metrics "github.com/rcrowley/go-metrics"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Kinds of deliberately invalid txs.
+const (
+	invalidChainID   = "chainID"     // Signed for the wrong chain.
+	invalidSignature = "signature"   // Zero signature values.
+	invalidGasLimit  = "gasLimit"    // Gas limit above the block gas limit.
+	invalidNonce     = "futureNonce" // Nonce far ahead of the account's.
+	invalidOversized = "oversized"   // Data above the tx pool size limit.
+	invalidDuplicate = "duplicate"   // Resend of the last sent tx.
+)
+
+var invalidKinds = []string{invalidChainID, invalidSignature, invalidGasLimit, invalidNonce, invalidOversized, invalidDuplicate}
+
+// invalidExpected lists the error classes which count as a correct rejection of
+// each kind. errClassNone means that it is correctly queued instead.
+var invalidExpected = map[string][]errClass{
+	invalidChainID:   {errClassInvalidSender},
+	invalidSignature: {errClassInvalidSender},
+	invalidGasLimit:  {errClassGasLimit},
+	invalidNonce:     {errClassNone, errClassNonceTooHigh}, // Usually queued.
+	invalidOversized: {errClassOversized},
+	invalidDuplicate: {errClassKnownTx, errClassNonceTooLow},
+}
+
+const (
+	futureNonceGap = 1 << 16
+	oversizedData  = txMaxSize + 1024
+)
+
+// Outcomes of invalid txs.
+const (
+	invalidSent       = "sent"
+	invalidRejected   = "rejected"   // With an expected error.
+	invalidUnexpected = "unexpected" // With some other error.
+	invalidQueued     = "queued"     // Accepted as expected, but never executable.
+	invalidAccepted   = "accepted"
+)
+
+var invalidOutcomes = []string{invalidSent, invalidRejected, invalidUnexpected, invalidQueued, invalidAccepted}
+
+func invalidMeter(kind, outcome string) metrics.Meter {
+	return metrics.GetOrRegisterMeter("meter/invalid/"+kind+"/"+outcome, nil)
+}
+
+// parseInvalidKinds parses a csv of invalid tx kinds.
+func parseInvalidKinds(csv string) ([]string, error) {
+	var kinds []string
+	for _, k := range strings.Split(csv, ",") {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		if _, ok := invalidExpected[k]; !ok {
+			return nil, fmt.Errorf("unknown invalid tx kind %q: must be one of %v", k, invalidKinds)
+		}
+		kinds = append(kinds, k)
+	}
+	return kinds, nil
+}
+
+// sendInvalid sends a random kind of invalid tx, and records whether it was
+// rejected as expected.
+func (s *Sender) sendInvalid(ctx context.Context) {
+	kind := s.invalidKinds[s.rng.Intn(len(s.invalidKinds))]
+	tx, err := s.invalidTx(ctx, kind)
+	if err != nil {
+		s.lgr.Warn("Failed to create invalid tx", zap.String("kind", kind), zap.Error(err))
+		return
+	}
+	if tx == nil {
+		return
+	}
+	invalidMeter(kind, invalidSent).Mark(1)
+	t := time.Now()
+	err = s.SendTransaction(ctx, tx)
+	s.recordTx(ctx, t, senderRole, s.acct.Address, tx, err)
+	if ctx.Err() != nil {
+		return
+	}
+	class := s.errs.classify(err)
+	var expected bool
+	for _, c := range invalidExpected[kind] {
+		if c == class {
+			expected = true
+			break
+		}
+	}
+	if err == nil && expected {
+		invalidMeter(kind, invalidQueued).Mark(1)
+		return
+	}
+	if err == nil {
+		invalidMeter(kind, invalidAccepted).Mark(1)
+		s.lgr.Warn("Invalid tx accepted", zap.String("kind", kind), zap.Stringer("hash", tx.Hash()))
+		if kind != invalidDuplicate {
+			spent.estimate(senderRole, tx)
+		}
+		if tx.Nonce() == s.nonce {
+			// Keep the sender's nonce consistent with the pool.
+			if s.nonceCheck > 0 {
+				s.pending.sent(s.nonce, tx.GasPrice())
+			}
+			s.nonce++
+		}
+		return
+	}
+	if expected {
+		invalidMeter(kind, invalidRejected).Mark(1)
+		return
+	}
+	invalidMeter(kind, invalidUnexpected).Mark(1)
+	s.lgr.Info("Invalid tx rejected with unexpected error", zap.String("kind", kind),
+		zap.String("class", string(class)), zap.Error(err))
+}
+
+// invalidTx returns a tx of the given kind, or nil if one can't be made yet.
+func (s *Sender) invalidTx(ctx context.Context, kind string) (*types.Transaction, error) {
+	if kind == invalidDuplicate {
+		return s.lastTx, nil
+	}
+	recv := s.recv[int(s.nonce)%len(s.recv)]
+	amount := new(big.Int).SetUint64(s.amount)
+	nonce, gas, price := s.nonce, s.gas, s.gasPrice
+	var data []byte
+	switch kind {
+	case invalidGasLimit:
+		if s.blockGasLimit == 0 {
+			h, err := s.HeaderByNumber(ctx, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get block gas limit: %v", err)
+			}
+			s.blockGasLimit = h.GasLimit
+		}
+		gas = s.blockGasLimit + 1
+	case invalidNonce:
+		nonce += futureNonceGap
+	case invalidOversized:
+		data = make([]byte, oversizedData)
+		_, _ = s.rng.Read(data)
+	}
+	tx := types.NewTransaction(nonce, recv, amount, gas, price, data)
+	switch kind {
+	case invalidChainID:
+		return s.AccountStore.signTxChainID(*s.acct, tx, new(big.Int).Add(s.AccountStore.chainID, big.NewInt(1)))
+	case invalidSignature:
+		signer := types.NewEIP155Signer(s.AccountStore.chainID)
+		return tx.WithSignature(signer, make([]byte, 65))
+	}
+	return s.AccountStore.SignTx(*s.acct, tx)
+}
+
+// invalidSnapshot holds cumulative invalid tx outcomes by kind.
+type invalidSnapshot map[string]map[string]int64
+
+func newInvalidSnapshot() invalidSnapshot {
+	s := make(invalidSnapshot)
+	for _, kind := range invalidKinds {
+		if invalidMeter(kind, invalidSent).Count() == 0 {
+			continue
+		}
+		s[kind] = make(map[string]int64, len(invalidOutcomes))
+		for _, o := range invalidOutcomes {
+			s[kind][o] = invalidMeter(kind, o).Count()
+		}
+	}
+	return s
+}
+
+func (s invalidSnapshot) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	for _, kind := range invalidKinds {
+		if counts, ok := s[kind]; ok {
+			_ = oe.AddObject(kind, zapcore.ObjectMarshalerFunc(func(oe zapcore.ObjectEncoder) error {
+				for _, o := range invalidOutcomes {
+					oe.AddInt64(o, counts[o])
+				}
+				return nil
+			}))
+		}
+	}
+	return nil
+}
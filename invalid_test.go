@@ -0,0 +1,62 @@
+package chainload
+
+import (
+	"context"
+	"math/big"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/gochain/gochain/v3/common"
+)
+
+func TestSender_sendInvalid(t *testing.T) {
+	_, node, accts, cleanup := newTestNode(t, 1e18)
+	defer cleanup()
+	if err := node.unlock(accts[0]); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	s := &Sender{Node: node, rng: rand.New(rand.NewSource(1)), acct: &accts[0], gasPrice: big.NewInt(2),
+		amount: 1, recv: []common.Address{{1}}, nonceCheck: time.Millisecond}
+	s.setLgr()
+	// For duplicate.
+	s.send(ctx, time.Now())
+	if s.lastTx == nil {
+		t.Fatal("expected a tx to be sent")
+	}
+
+	for _, test := range []struct {
+		kind    string
+		outcome string
+	}{
+		{invalidChainID, invalidRejected},
+		{invalidSignature, invalidRejected},
+		{invalidGasLimit, invalidRejected},
+		{invalidNonce, invalidQueued},
+		{invalidOversized, invalidRejected},
+		{invalidDuplicate, invalidRejected},
+	} {
+		t.Run(test.kind, func(t *testing.T) {
+			before := make(map[string]int64)
+			for _, o := range invalidOutcomes {
+				before[o] = invalidMeter(test.kind, o).Count()
+			}
+			nonce := s.nonce
+			s.invalidKinds = []string{test.kind}
+			s.sendInvalid(ctx)
+			for _, o := range invalidOutcomes {
+				var want int64
+				if o == invalidSent || o == test.outcome {
+					want = 1
+				}
+				if got := invalidMeter(test.kind, o).Count() - before[o]; got != want {
+					t.Errorf("expected %d %s but got %d", want, o, got)
+				}
+			}
+			if s.nonce != nonce {
+				t.Errorf("expected nonce %d to be unchanged but got %d", nonce, s.nonce)
+			}
+		})
+	}
+}
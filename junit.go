@@ -0,0 +1,185 @@
+package chainload
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Stages of a run, reported as JUnit test cases.
+const (
+	stageSeeders = "seeders" // Seeder accounts and the test contract.
+	stageSenders = "senders" // Every sender assigned an account and funded.
+	stageLoad    = "load"    // Sending, until the duration, budget or an interrupt.
+)
+
+// stage is a timed phase of a run. It is safe for concurrent use.
+type stage struct {
+	name string
+
+	mu         sync.Mutex
+	start, end time.Time
+	failure    string
+}
+
+// begin marks the start of the stage.
+func (s *stage) begin() {
+	s.mu.Lock()
+	s.start = time.Now()
+	s.mu.Unlock()
+}
+
+// finish marks the end of the stage, and whether it failed.
+func (s *stage) finish(failure string) {
+	s.mu.Lock()
+	if s.end.IsZero() {
+		s.end = time.Now()
+		s.failure = failure
+	}
+	s.mu.Unlock()
+}
+
+// newStages returns the stages of a run, in order.
+func newStages() []*stage {
+	return []*stage{{name: stageSeeders}, {name: stageSenders}, {name: stageLoad}}
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+func junitTime(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// newJUnit returns a test suite of the stages of a run which started at start,
+// and the criteria checked over the load. Stages which never finished fail with
+// runErr, and stages which never began are skipped.
+func newJUnit(config map[string]interface{}, start, end time.Time, stages []*stage, checks sloResult, load time.Duration, runErr error) *junitTestSuites {
+	suite := junitTestSuite{
+		Name:      "chainload",
+		Time:      junitTime(end.Sub(start)),
+		Timestamp: start.UTC().Format("2006-01-02T15:04:05"),
+	}
+	for _, s := range stages {
+		s.mu.Lock()
+		tc := junitTestCase{Name: s.name, Classname: "chainload.stage"}
+		switch {
+		case s.start.IsZero():
+			tc.Time = junitTime(0)
+			tc.Skipped = &junitSkipped{Message: "not reached"}
+		case s.end.IsZero():
+			tc.Time = junitTime(end.Sub(s.start))
+			msg := "did not finish"
+			if runErr != nil {
+				msg = runErr.Error()
+			}
+			tc.Failure = &junitFailure{Message: msg}
+		default:
+			tc.Time = junitTime(s.end.Sub(s.start))
+			if s.failure != "" {
+				tc.Failure = &junitFailure{Message: s.failure}
+			}
+		}
+		s.mu.Unlock()
+		suite.Cases = append(suite.Cases, tc)
+	}
+	for _, c := range checks {
+		tc := junitTestCase{Name: c.name, Classname: "chainload.slo", Time: junitTime(load)}
+		if !c.pass {
+			msg := fmt.Sprintf("want %s, got %s", c.want, c.got)
+			if c.failed > 0 {
+				msg += fmt.Sprintf(" (%d of %d intervals failed)", c.failed, c.intervals)
+			}
+			tc.Failure = &junitFailure{Message: msg}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	for _, tc := range suite.Cases {
+		suite.Tests++
+		if tc.Failure != nil {
+			suite.Failures++
+		}
+		if tc.Skipped != nil {
+			suite.Skipped++
+		}
+	}
+	suite.Properties = junitProperties("", config)
+	return &junitTestSuites{
+		Name:     "chainload",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+}
+
+// junitProperties flattens config into properties, with nested names joined by
+// dots.
+func junitProperties(prefix string, config map[string]interface{}) []junitProperty {
+	var ps []junitProperty
+	for _, k := range sortedKeys(config) {
+		if m, ok := config[k].(map[string]interface{}); ok {
+			ps = append(ps, junitProperties(prefix+k+".", m)...)
+			continue
+		}
+		ps = append(ps, junitProperty{Name: prefix + k, Value: fmt.Sprint(config[k])})
+	}
+	return ps
+}
+
+// write writes the test suites to path as XML.
+func (j *junitTestSuites) write(path string) error {
+	b, err := xml.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, append([]byte(xml.Header), append(b, '\n')...), 0644); err != nil {
+		return fmt.Errorf("failed to write junit file: %v", err)
+	}
+	return os.Rename(tmp, path)
+}
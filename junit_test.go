@@ -0,0 +1,73 @@
+package chainload
+
+import (
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJUnit_write(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chainload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := time.Now()
+	stages := newStages()
+	stages[0].begin()
+	stages[0].finish("")
+	stages[1].begin()
+	checks := sloResult{
+		{name: "minTps", want: ">= 90", got: "95", pass: true},
+		{name: "maxP99", want: "<= 1s", got: "2s", failed: 1, intervals: 3},
+	}
+	j := newJUnit(map[string]interface{}{"tps": 100}, start, start.Add(time.Minute), stages, checks, time.Minute, errors.New("interrupted"))
+	path := filepath.Join(dir, "junit.xml")
+	if err := j.write(path); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got junitTestSuites
+	if err := xml.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Tests != 5 || got.Failures != 2 || got.Skipped != 1 {
+		t.Errorf("expected 5 tests, 2 failures and 1 skipped but got %d, %d and %d", got.Tests, got.Failures, got.Skipped)
+	}
+	cases := got.Suites[0].Cases
+	for i, exp := range []struct {
+		name    string
+		failure string
+		skipped bool
+	}{
+		{name: stageSeeders},
+		{name: stageSenders, failure: "interrupted"},
+		{name: stageLoad, skipped: true},
+		{name: "minTps"},
+		{name: "maxP99", failure: "want <= 1s, got 2s (1 of 3 intervals failed)"},
+	} {
+		tc := cases[i]
+		if tc.Name != exp.name {
+			t.Errorf("%d: expected %s but got %s", i, exp.name, tc.Name)
+		}
+		var failure string
+		if tc.Failure != nil {
+			failure = tc.Failure.Message
+		}
+		if failure != exp.failure {
+			t.Errorf("%s: expected failure %q but got %q", tc.Name, exp.failure, failure)
+		}
+		if (tc.Skipped != nil) != exp.skipped {
+			t.Errorf("%s: expected skipped %t", tc.Name, exp.skipped)
+		}
+	}
+}
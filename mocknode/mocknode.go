@@ -0,0 +1,687 @@
+// Package mocknode provides an in-memory GoChain JSON-RPC node for offline
+// tests. It tracks balances and nonces, keeps a tx pool with limits, produces
+// blocks on a timer, and can inject errors and delays into any method.
+package mocknode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gochain/gochain/v3/common"
+	"github.com/gochain/gochain/v3/common/hexutil"
+	"github.com/gochain/gochain/v3/core"
+	"github.com/gochain/gochain/v3/core/types"
+	"github.com/gochain/gochain/v3/crypto"
+	"github.com/gochain/gochain/v3/rlp"
+)
+
+// Config configures a Node. Zero values are replaced by defaults.
+type Config struct {
+	ChainID   *big.Int                    // Default 1234.
+	BlockTime time.Duration               // Default 100ms.
+	GasLimit  uint64                      // Block gas limit. Default 8,000,000.
+	GasPrice  *big.Int                    // Suggested and minimum gas price. Default 1.
+	PoolLimit int                         // Maximum pooled txs. Default 4096.
+	PriceBump uint64                      // Minimum replacement price increase, in percent. Default 10.
+	Alloc     map[common.Address]*big.Int // Initial balances.
+}
+
+// Fault is an injected failure of matching requests.
+type Fault struct {
+	Method  string        // JSON-RPC method, or "" for all.
+	Count   int           // Matching requests to fail. 0 for unlimited.
+	Code    int           // JSON-RPC error code. Default -32000.
+	Message string        // JSON-RPC error message, e.g. "nonce too low".
+	Status  int           // HTTP status to respond with instead, e.g. 429 or 503.
+	Close   bool          // Close the connection without responding instead.
+	Delay   time.Duration // Delay before responding (or failing).
+}
+
+// Node is an in-memory JSON-RPC node, served over HTTP.
+type Node struct {
+	URL string
+
+	cfg  Config
+	srv  *httptest.Server
+	stop chan struct{}
+	done chan struct{}
+
+	mu       sync.Mutex
+	balances map[common.Address]*big.Int
+	nonces   map[common.Address]uint64
+	pool     map[common.Address]map[uint64]*types.Transaction
+	poolLen  int
+	known    map[common.Hash]struct{}
+	blocks   []*types.Block
+	byHash   map[common.Hash]*types.Block
+	receipts map[common.Hash]*types.Receipt
+	faults   []*Fault
+	calls    map[string]int
+	paused   bool
+}
+
+// New starts a Node. It must be closed.
+func New(cfg Config) *Node {
+	if cfg.ChainID == nil {
+		cfg.ChainID = big.NewInt(1234)
+	}
+	if cfg.BlockTime == 0 {
+		cfg.BlockTime = 100 * time.Millisecond
+	}
+	if cfg.GasLimit == 0 {
+		cfg.GasLimit = 8000000
+	}
+	if cfg.GasPrice == nil {
+		cfg.GasPrice = big.NewInt(1)
+	}
+	if cfg.PoolLimit == 0 {
+		cfg.PoolLimit = 4096
+	}
+	if cfg.PriceBump == 0 {
+		cfg.PriceBump = 10
+	}
+	n := &Node{
+		cfg:      cfg,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		balances: make(map[common.Address]*big.Int),
+		nonces:   make(map[common.Address]uint64),
+		pool:     make(map[common.Address]map[uint64]*types.Transaction),
+		known:    make(map[common.Hash]struct{}),
+		byHash:   make(map[common.Hash]*types.Block),
+		receipts: make(map[common.Hash]*types.Receipt),
+		calls:    make(map[string]int),
+	}
+	for addr, bal := range cfg.Alloc {
+		n.balances[addr] = new(big.Int).Set(bal)
+	}
+	n.addBlock(types.NewBlock(n.header(0, common.Hash{}), nil, nil, nil))
+	n.srv = httptest.NewServer(http.HandlerFunc(n.serveHTTP))
+	n.URL = n.srv.URL
+	go n.mine()
+	return n
+}
+
+// Close stops producing blocks and shuts down the server.
+func (n *Node) Close() {
+	close(n.stop)
+	<-n.done
+	n.srv.CloseClientConnections()
+	n.srv.Close()
+}
+
+// Inject adds a fault. Faults are matched in the order they were injected.
+func (n *Node) Inject(f Fault) {
+	n.mu.Lock()
+	n.faults = append(n.faults, &f)
+	n.mu.Unlock()
+}
+
+// ClearFaults removes all faults.
+func (n *Node) ClearFaults() {
+	n.mu.Lock()
+	n.faults = nil
+	n.mu.Unlock()
+}
+
+// SetBalance sets the balance of addr.
+func (n *Node) SetBalance(addr common.Address, bal *big.Int) {
+	n.mu.Lock()
+	n.balances[addr] = new(big.Int).Set(bal)
+	n.mu.Unlock()
+}
+
+// Balance returns the confirmed balance of addr.
+func (n *Node) Balance(addr common.Address) *big.Int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.balance(addr)
+}
+
+// Nonce returns the confirmed nonce of addr.
+func (n *Node) Nonce(addr common.Address) uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.nonces[addr]
+}
+
+// PoolLen returns the number of pooled txs.
+func (n *Node) PoolLen() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.poolLen
+}
+
+// BlockNumber returns the latest block number.
+func (n *Node) BlockNumber() uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.latest().NumberU64()
+}
+
+// Pooled returns the pooled tx from addr with nonce, or nil.
+func (n *Node) Pooled(addr common.Address, nonce uint64) *types.Transaction {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.pool[addr][nonce]
+}
+
+// Drop discards the pooled tx from addr with nonce, like a node evicting it, so
+// that it is never mined.
+func (n *Node) Drop(addr common.Address, nonce uint64) {
+	n.mu.Lock()
+	n.remove(addr, nonce)
+	n.mu.Unlock()
+}
+
+// Pause stops or resumes including pooled txs in blocks. Empty blocks are still
+// produced while paused, like a chain whose miners ignore the pool.
+func (n *Node) Pause(paused bool) {
+	n.mu.Lock()
+	n.paused = paused
+	n.mu.Unlock()
+}
+
+// Calls returns the number of requests for method.
+func (n *Node) Calls(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls[method]
+}
+
+func (n *Node) mine() {
+	defer close(n.done)
+	t := time.NewTicker(n.cfg.BlockTime)
+	defer t.Stop()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-t.C:
+			n.Mine()
+		}
+	}
+}
+
+// Mine produces a block from the executable pooled txs.
+func (n *Node) Mine() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	parent := n.latest()
+	header := n.header(parent.NumberU64()+1, parent.Hash())
+
+	senders := make([]common.Address, 0, len(n.pool))
+	for addr := range n.pool {
+		senders = append(senders, addr)
+	}
+	sort.Slice(senders, func(i, j int) bool {
+		return bytes.Compare(senders[i][:], senders[j][:]) < 0
+	})
+	var txs []*types.Transaction
+	var receipts []*types.Receipt
+	if n.paused {
+		senders = nil
+	}
+	for _, from := range senders {
+		txs, receipts = n.execute(header, from, txs, receipts)
+	}
+	b := types.NewBlock(header, txs, nil, receipts)
+	for i, r := range receipts {
+		r.BlockHash = b.Hash()
+		r.BlockNumber = b.Number()
+		r.TransactionIndex = uint(i)
+	}
+	n.addBlock(b)
+}
+
+// execute applies the executable pooled txs from sender to the state, within
+// the header's gas limit.
+func (n *Node) execute(header *types.Header, from common.Address, txs []*types.Transaction, receipts []*types.Receipt) ([]*types.Transaction, []*types.Receipt) {
+	pending := n.pool[from]
+	for nonce := range pending {
+		if nonce < n.nonces[from] {
+			n.remove(from, nonce)
+		}
+	}
+	for {
+		nonce := n.nonces[from]
+		tx, ok := pending[nonce]
+		if !ok {
+			break
+		}
+		gas := gasUsed(tx)
+		if header.GasUsed+gas > header.GasLimit {
+			break
+		}
+		n.remove(from, nonce)
+		fee := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(gas))
+		cost := fee.Add(fee, tx.Value())
+		if n.balance(from).Cmp(cost) < 0 {
+			// Overdrawn by earlier txs. Drop it.
+			continue
+		}
+		n.balances[from] = cost.Sub(n.balance(from), cost)
+		r := &types.Receipt{
+			Status: types.ReceiptStatusSuccessful,
+			TxHash: tx.Hash(),
+			Logs:   []*types.Log{},
+		}
+		if to := tx.To(); to != nil {
+			n.balances[*to] = new(big.Int).Add(n.balance(*to), tx.Value())
+		} else {
+			r.ContractAddress = crypto.CreateAddress(from, nonce)
+		}
+		n.nonces[from] = nonce + 1
+		header.GasUsed += gas
+		r.GasUsed = gas
+		r.CumulativeGasUsed = header.GasUsed
+		txs = append(txs, tx)
+		receipts = append(receipts, r)
+		n.receipts[tx.Hash()] = r
+	}
+	return txs, receipts
+}
+
+func (n *Node) remove(from common.Address, nonce uint64) {
+	if tx, ok := n.pool[from][nonce]; ok {
+		delete(n.known, tx.Hash())
+		delete(n.pool[from], nonce)
+		n.poolLen--
+		if len(n.pool[from]) == 0 {
+			delete(n.pool, from)
+		}
+	}
+}
+
+func (n *Node) header(number uint64, parent common.Hash) *types.Header {
+	return &types.Header{
+		ParentHash: parent,
+		Difficulty: big.NewInt(1),
+		Number:     new(big.Int).SetUint64(number),
+		GasLimit:   n.cfg.GasLimit,
+		Time:       big.NewInt(time.Now().Unix()),
+	}
+}
+
+func (n *Node) addBlock(b *types.Block) {
+	n.blocks = append(n.blocks, b)
+	n.byHash[b.Hash()] = b
+}
+
+func (n *Node) latest() *types.Block {
+	return n.blocks[len(n.blocks)-1]
+}
+
+func (n *Node) balance(addr common.Address) *big.Int {
+	if b, ok := n.balances[addr]; ok {
+		return b
+	}
+	return new(big.Int)
+}
+
+// pendingNonce returns the nonce after the executable pooled txs from addr.
+func (n *Node) pendingNonce(addr common.Address) uint64 {
+	nonce := n.nonces[addr]
+	for {
+		if _, ok := n.pool[addr][nonce]; !ok {
+			return nonce
+		}
+		nonce++
+	}
+}
+
+// pendingBalance returns the balance of addr after the executable pooled txs.
+func (n *Node) pendingBalance(addr common.Address) *big.Int {
+	bal := new(big.Int).Set(n.balance(addr))
+	for from, txs := range n.pool {
+		for nonce := n.nonces[from]; ; nonce++ {
+			tx, ok := txs[nonce]
+			if !ok {
+				break
+			}
+			if from == addr {
+				fee := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(gasUsed(tx)))
+				bal.Sub(bal, fee.Add(fee, tx.Value()))
+			}
+			if to := tx.To(); to != nil && *to == addr {
+				bal.Add(bal, tx.Value())
+			}
+		}
+	}
+	if bal.Sign() < 0 {
+		bal.SetInt64(0)
+	}
+	return bal
+}
+
+// gasUsed returns the gas used by tx, which is just its intrinsic gas, since
+// code is not executed.
+func gasUsed(tx *types.Transaction) uint64 {
+	gas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, true)
+	if err != nil || gas > tx.Gas() {
+		return tx.Gas()
+	}
+	return gas
+}
+
+// add validates tx like the gochain tx pool, and adds it.
+func (n *Node) add(tx *types.Transaction) error {
+	if tx.Size() > 32*1024 {
+		return core.ErrOversizedData
+	}
+	if tx.Value().Sign() < 0 {
+		return core.ErrNegativeValue
+	}
+	if tx.Gas() > n.cfg.GasLimit {
+		return core.ErrGasLimit
+	}
+	from, err := types.Sender(types.NewEIP155Signer(n.cfg.ChainID), tx)
+	if err != nil {
+		return core.ErrInvalidSender
+	}
+	if _, ok := n.known[tx.Hash()]; ok {
+		return fmt.Errorf("known transaction: %x", tx.Hash())
+	}
+	if tx.GasPrice().Cmp(n.cfg.GasPrice) < 0 {
+		return core.ErrUnderpriced
+	}
+	if n.nonces[from] > tx.Nonce() {
+		return core.ErrNonceTooLow
+	}
+	cost := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas()))
+	if n.balance(from).Cmp(cost.Add(cost, tx.Value())) < 0 {
+		return core.ErrInsufficientFunds
+	}
+	if intr, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, true); err != nil {
+		return err
+	} else if tx.Gas() < intr {
+		return core.ErrIntrinsicGas
+	}
+	if old, ok := n.pool[from][tx.Nonce()]; ok {
+		min := new(big.Int).Mul(old.GasPrice(), new(big.Int).SetUint64(100+n.cfg.PriceBump))
+		if new(big.Int).Mul(tx.GasPrice(), big.NewInt(100)).Cmp(min) < 0 {
+			return core.ErrReplaceUnderpriced
+		}
+		n.remove(from, tx.Nonce())
+	} else if n.poolLen >= n.cfg.PoolLimit {
+		return core.ErrPoolLimit
+	}
+	if n.pool[from] == nil {
+		n.pool[from] = make(map[uint64]*types.Transaction)
+	}
+	n.pool[from][tx.Nonce()] = tx
+	n.poolLen++
+	n.known[tx.Hash()] = struct{}{}
+	return nil
+}
+
+type request struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type response struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (n *Node) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := response{Version: "2.0", ID: req.ID}
+	if f := n.fault(req.Method); f != nil {
+		if f.Delay > 0 {
+			select {
+			case <-time.After(f.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		switch {
+		case f.Close:
+			if hj, ok := w.(http.Hijacker); ok {
+				if conn, _, err := hj.Hijack(); err == nil {
+					_ = conn.Close()
+					return
+				}
+			}
+			http.Error(w, "connection closed", http.StatusServiceUnavailable)
+			return
+		case f.Status != 0:
+			http.Error(w, http.StatusText(f.Status), f.Status)
+			return
+		case f.Message != "":
+			code := f.Code
+			if code == 0 {
+				code = -32000
+			}
+			resp.Error = &rpcError{Code: code, Message: f.Message}
+			n.write(w, resp)
+			return
+		}
+	}
+	result, err := n.call(req.Method, req.Params)
+	if err != nil {
+		code := -32000
+		if e, ok := err.(*rpcError); ok {
+			code = e.Code
+		}
+		resp.Error = &rpcError{Code: code, Message: err.Error()}
+	} else if result == nil {
+		resp.Result = json.RawMessage("null")
+	} else {
+		resp.Result = result
+	}
+	n.write(w, resp)
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+func (n *Node) write(w http.ResponseWriter, resp response) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// fault returns the first matching fault, and counts its use.
+func (n *Node) fault(method string) *Fault {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls[method]++
+	for i, f := range n.faults {
+		if f.Method != "" && f.Method != method {
+			continue
+		}
+		if f.Count > 0 {
+			if f.Count--; f.Count == 0 {
+				n.faults = append(n.faults[:i:i], n.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+func (n *Node) call(method string, params []json.RawMessage) (interface{}, error) {
+	arg := func(i int, v interface{}) error {
+		if i >= len(params) {
+			return &rpcError{Code: -32602, Message: fmt.Sprintf("missing value for required argument %d", i)}
+		}
+		if err := json.Unmarshal(params[i], v); err != nil {
+			return &rpcError{Code: -32602, Message: fmt.Sprintf("invalid argument %d: %v", i, err)}
+		}
+		return nil
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	switch method {
+	case "eth_chainId":
+		return (*hexutil.Big)(n.cfg.ChainID), nil
+	case "net_version":
+		return n.cfg.ChainID.String(), nil
+	case "eth_blockNumber":
+		return hexutil.Uint64(n.latest().NumberU64()), nil
+	case "eth_gasPrice":
+		return (*hexutil.Big)(n.cfg.GasPrice), nil
+	case "eth_getBalance":
+		var addr common.Address
+		var tag string
+		if err := arg(0, &addr); err != nil {
+			return nil, err
+		}
+		if err := arg(1, &tag); err != nil {
+			return nil, err
+		}
+		if tag == "pending" {
+			return (*hexutil.Big)(n.pendingBalance(addr)), nil
+		}
+		return (*hexutil.Big)(n.balance(addr)), nil
+	case "eth_getTransactionCount":
+		var addr common.Address
+		var tag string
+		if err := arg(0, &addr); err != nil {
+			return nil, err
+		}
+		if err := arg(1, &tag); err != nil {
+			return nil, err
+		}
+		if tag == "pending" {
+			return hexutil.Uint64(n.pendingNonce(addr)), nil
+		}
+		return hexutil.Uint64(n.nonces[addr]), nil
+	case "eth_sendRawTransaction":
+		var raw hexutil.Bytes
+		if err := arg(0, &raw); err != nil {
+			return nil, err
+		}
+		tx := new(types.Transaction)
+		if err := rlp.DecodeBytes(raw, tx); err != nil {
+			return nil, err
+		}
+		if err := n.add(tx); err != nil {
+			return nil, err
+		}
+		return tx.Hash(), nil
+	case "eth_getTransactionReceipt":
+		var hash common.Hash
+		if err := arg(0, &hash); err != nil {
+			return nil, err
+		}
+		if r, ok := n.receipts[hash]; ok {
+			return r, nil
+		}
+		return nil, nil
+	case "eth_getBlockByNumber":
+		var tag string
+		var full bool
+		if err := arg(0, &tag); err != nil {
+			return nil, err
+		}
+		if err := arg(1, &full); err != nil {
+			return nil, err
+		}
+		b := n.latest()
+		if tag != "latest" && tag != "pending" {
+			num, err := hexutil.DecodeUint64(tag)
+			if err != nil {
+				return nil, &rpcError{Code: -32602, Message: err.Error()}
+			}
+			if num >= uint64(len(n.blocks)) {
+				return nil, nil
+			}
+			b = n.blocks[num]
+		}
+		return marshalBlock(b, full)
+	case "eth_getBlockByHash":
+		var hash common.Hash
+		var full bool
+		if err := arg(0, &hash); err != nil {
+			return nil, err
+		}
+		if err := arg(1, &full); err != nil {
+			return nil, err
+		}
+		b, ok := n.byHash[hash]
+		if !ok {
+			return nil, nil
+		}
+		return marshalBlock(b, full)
+	case "eth_call":
+		// Like the chainload test contract: return the block number.
+		return hexutil.Bytes(common.LeftPadBytes(n.latest().Number().Bytes(), 32)), nil
+	case "eth_estimateGas":
+		return hexutil.Uint64(21000), nil
+	case "eth_getLogs":
+		return []*types.Log{}, nil
+	}
+	return nil, &rpcError{Code: -32601, Message: fmt.Sprintf("the method %s does not exist/is not available", method)}
+}
+
+// marshalBlock returns the JSON-RPC representation of b, with either full
+// transactions or just their hashes.
+func marshalBlock(b *types.Block, full bool) (json.RawMessage, error) {
+	h, err := json.Marshal(b.Header())
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(h, &fields); err != nil {
+		return nil, err
+	}
+	var txs interface{}
+	if full {
+		txs = b.Transactions()
+	} else {
+		hashes := make([]common.Hash, 0, len(b.Transactions()))
+		for _, tx := range b.Transactions() {
+			hashes = append(hashes, tx.Hash())
+		}
+		txs = hashes
+	}
+	for k, v := range map[string]interface{}{
+		"hash":         b.Hash(),
+		"transactions": txs,
+		"uncles":       []common.Hash{},
+		"size":         hexutil.Uint64(b.Size()),
+	} {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		fields[k] = raw
+	}
+	out, err := json.Marshal(fields)
+	return json.RawMessage(out), err
+}
+
+// ErrorMessages maps the errors handled by chainload to the messages the
+// gochain tx pool responds with, for use with Fault.
+var ErrorMessages = map[string]string{
+	"nonceTooLow":        core.ErrNonceTooLow.Error(),
+	"knownTx":            "known transaction: " + strings.Repeat("0", 64),
+	"replaceUnderpriced": core.ErrReplaceUnderpriced.Error(),
+	"underpriced":        core.ErrUnderpriced.Error(),
+	"lowFunds":           core.ErrInsufficientFunds.Error(),
+	"poolLimit":          core.ErrPoolLimit.Error(),
+	"invalidSender":      core.ErrInvalidSender.Error(),
+	"gasLimit":           core.ErrGasLimit.Error(),
+	"oversized":          core.ErrOversizedData.Error(),
+}
@@ -0,0 +1,91 @@
+package mocknode
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gochain/gochain/v3/common"
+	"github.com/gochain/gochain/v3/core/types"
+	"github.com/gochain/gochain/v3/crypto"
+	"github.com/gochain/gochain/v3/goclient"
+)
+
+func TestNode(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	to := common.HexToAddress("0x1")
+	n := New(Config{BlockTime: time.Hour, Alloc: map[common.Address]*big.Int{from: big.NewInt(1e18)}})
+	defer n.Close()
+	ctx := context.Background()
+	client, err := goclient.Dial(n.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer := types.NewEIP155Signer(big.NewInt(1234))
+	send := func(nonce uint64, price int64) (*types.Transaction, error) {
+		tx, err := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(100), 21000, big.NewInt(price), nil), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx, client.SendTransaction(ctx, tx)
+	}
+	tx, err := send(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		nonce uint64
+		price int64
+		err   string
+	}{
+		{0, 100, "known transaction"},
+		{0, 105, "replacement transaction underpriced"},
+		{0, 0, "transaction underpriced"},
+	} {
+		if _, err := send(test.nonce, test.price); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("expected %q but got %v", test.err, err)
+		}
+	}
+	if nonce, err := client.PendingNonceAt(ctx, from); err != nil {
+		t.Fatal(err)
+	} else if nonce != 1 {
+		t.Errorf("expected pending nonce 1 but got %d", nonce)
+	}
+
+	n.Mine()
+	r, err := client.TransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.GasUsed != 21000 || r.BlockNumber.Uint64() != 1 {
+		t.Errorf("unexpected receipt: gas %d block %d", r.GasUsed, r.BlockNumber)
+	}
+	if bal := n.Balance(to); bal.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("expected balance 100 but got %s", bal)
+	}
+	if _, err := send(0, 200); err == nil || !strings.Contains(err.Error(), "nonce too low") {
+		t.Errorf("expected nonce too low but got %v", err)
+	}
+	b, err := client.BlockByNumber(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Transactions()) != 1 {
+		t.Errorf("expected 1 tx in block but got %d", len(b.Transactions()))
+	}
+
+	n.Inject(Fault{Method: "eth_blockNumber", Count: 1, Status: 503})
+	if _, err := client.LatestBlockNumber(ctx); err == nil || !strings.HasPrefix(err.Error(), "503 ") {
+		t.Errorf("expected 503 but got %v", err)
+	}
+	if _, err := client.LatestBlockNumber(ctx); err != nil {
+		t.Errorf("expected fault to be cleared: %v", err)
+	}
+}
@@ -3,8 +3,10 @@ package chainload
 import (
 	"context"
 	"math/big"
+	"math/rand"
 	"time"
 
+	"github.com/gochain/gochain/v3"
 	"github.com/gochain/gochain/v3/accounts"
 	"github.com/gochain/gochain/v3/common"
 	"github.com/gochain/gochain/v3/core/types"
@@ -12,16 +14,43 @@ import (
 	"go.uber.org/zap"
 )
 
+// Client is the subset of goclient.Client used by chainload.
+type Client interface {
+	LatestBlockNumber(ctx context.Context) (*big.Int, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	PendingBalanceAt(ctx context.Context, account common.Address) (*big.Int, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	CallContract(ctx context.Context, msg gochain.CallMsg, blockNumber *big.Int) ([]byte, error)
+	FilterLogs(ctx context.Context, q gochain.FilterQuery) ([]types.Log, error)
+}
+
+var _ Client = (*goclient.Client)(nil)
+
 type Node struct {
 	lgr    *zap.Logger
 	Number int
 	gas    uint64
-	*goclient.Client
+	Client
 	*AccountStore
 	SeedCh chan SeedReq
+	errs   *errClassifier
+
+	breaker *breaker // Wraps Client. Optional.
+
+	receipts chan *pendingReceipt // Sent txs to watch for receipts. Optional.
+	record   *recorder            // Records sent txs. Optional.
 }
 
-func (n *Node) refund(ctx context.Context, acct accounts.Account, nonce uint64, seed common.Address) (*big.Int, error) {
+// refund sends the balance of acct, less the fee, to seed. The gas limit is
+// randomized using rng.
+func (n *Node) refund(ctx context.Context, rng *rand.Rand, acct accounts.Account, nonce uint64, seed common.Address) (*big.Int, error) {
 	t := time.Now()
 	bal, err := n.PendingBalanceAt(ctx, acct.Address)
 	if err != nil {
@@ -36,7 +65,7 @@ func (n *Node) refund(ctx context.Context, acct accounts.Account, nonce uint64,
 	}
 	suggestGasPriceTimer.UpdateSince(t)
 
-	gas := randBetween(n.gas, 2*n.gas)
+	gas := randBetween(rng, n.gas, 2*n.gas)
 	var amount big.Int
 	amount.Mul(new(big.Int).SetUint64(gas), gasPrice)
 	amount.Sub(bal, &amount)
@@ -52,13 +81,10 @@ func (n *Node) refund(ctx context.Context, acct accounts.Account, nonce uint64,
 	}
 	signTxTimer.UpdateSince(t)
 
-	t = time.Now()
-	err = n.SendTransaction(ctx, tx)
-	if err != nil {
-		sendTxErrMeter.Mark(1)
+	if err := n.sendTx(ctx, refundRole, acct.Address, tx); err != nil {
 		return nil, err
 	}
-	sendTxTimer.UpdateSince(t)
+	n.noteBalance(acct.Address, new(big.Int).Sub(bal, &amount))
 
 	return &amount, nil
 }
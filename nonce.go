@@ -0,0 +1,138 @@
+package chainload
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/gochain/gochain/v3/core/types"
+	"github.com/gochain/gochain/v3/params"
+	metrics "github.com/rcrowley/go-metrics"
+	"go.uber.org/zap"
+)
+
+var (
+	nonceAtTimer   = metrics.GetOrRegisterTimer("timer/nonceAt", nil)
+	gapFillMeter   = metrics.GetOrRegisterMeter("meter/gapFill", nil)
+	nonceSyncMeter = metrics.GetOrRegisterMeter("meter/nonceSync", nil)
+)
+
+// maxGapFill limits the no-op txs sent per nonce check.
+const maxGapFill = 16
+
+// pendingTxs tracks a sender's unconfirmed nonces: those definitely accepted
+// by the node (with their gas prices), and those whose sends failed in transit
+// and may or may not have been accepted. It also tracks how long the confirmed
+// nonce has gone without advancing.
+type pendingTxs struct {
+	prices      map[uint64]*big.Int // Accepted nonces.
+	uncertain   map[uint64]struct{}
+	confirmed   uint64    // Last observed confirmed nonce.
+	confirmedAt time.Time // When confirmed last advanced.
+}
+
+func (p *pendingTxs) reset() {
+	p.prices = make(map[uint64]*big.Int)
+	p.uncertain = make(map[uint64]struct{})
+	p.confirmed = 0
+	p.confirmedAt = time.Time{}
+}
+
+// sent records that nonce was accepted with price.
+func (p *pendingTxs) sent(nonce uint64, price *big.Int) {
+	if p.prices == nil {
+		p.reset()
+	}
+	p.prices[nonce] = price
+	delete(p.uncertain, nonce)
+}
+
+// unsure records that the outcome of sending nonce is unknown.
+func (p *pendingTxs) unsure(nonce uint64) {
+	if p.uncertain == nil {
+		p.reset()
+	}
+	p.uncertain[nonce] = struct{}{}
+}
+
+// observe records the confirmed nonce, and returns true if it advanced.
+func (p *pendingTxs) observe(confirmed uint64) bool {
+	if !p.confirmedAt.IsZero() && confirmed <= p.confirmed {
+		return false
+	}
+	p.confirmed = confirmed
+	p.confirmedAt = time.Now()
+	for n := range p.prices {
+		if n < confirmed {
+			delete(p.prices, n)
+		}
+	}
+	for n := range p.uncertain {
+		if n < confirmed {
+			delete(p.uncertain, n)
+		}
+	}
+	return true
+}
+
+// checkNonces compares the local nonce with the node's pending nonce. Gaps, which
+// leave later txs queued indefinitely, are filled with no-ops. A lagging local
+// nonce is advanced. Otherwise, stuck txs are replaced if enabled.
+func (s *Sender) checkNonces(ctx context.Context, force bool) {
+	t := time.Now()
+	pending, err := s.PendingNonceAt(ctx, s.acct.Address)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.lgr.Warn("Failed to get pending nonce", zap.Error(err))
+		}
+		return
+	}
+	pendingNonceAtTimer.UpdateSince(t)
+	switch {
+	case pending < s.nonce:
+		s.fillGaps(ctx, pending)
+	case pending > s.nonce:
+		// Sends which appeared to fail were accepted.
+		s.lgr.Info("Advancing lagging nonce", zap.Uint64("nonce", pending), zap.Uint64("old", s.nonce))
+		nonceSyncMeter.Mark(1)
+		s.nonce = pending
+	case s.stuckAfter > 0:
+		s.checkStuck(ctx, force)
+	}
+}
+
+// fillGaps sends no-op self-transfers for the missing nonces from the pending
+// nonce up to the local nonce, skipping those known to have been accepted
+// (which are presumably queued behind the gap).
+func (s *Sender) fillGaps(ctx context.Context, from uint64) {
+	unsure := len(s.pending.uncertain)
+	var filled int
+	for n := from; n < s.nonce && filled < maxGapFill; n++ {
+		if _, ok := s.pending.prices[n]; ok && n != from {
+			continue
+		}
+		tx := types.NewTransaction(n, s.acct.Address, new(big.Int), params.TxGas, s.gasPrice, nil)
+		tx, err := s.AccountStore.SignTx(*s.acct, tx)
+		if err != nil {
+			s.lgr.Warn("Failed to sign gap tx", zap.Error(err))
+			return
+		}
+		if err := s.sendTx(ctx, fillRole, s.acct.Address, tx); err != nil {
+			switch s.errs.classify(err) {
+			case errClassKnownTx, errClassReplaceUnderpriced, errClassNonceTooLow:
+				continue // Not actually a gap.
+			}
+			if ctx.Err() == nil {
+				s.lgr.Warn("Failed to fill nonce gap", zap.Uint64("nonce", n), zap.Error(err))
+			}
+			break
+		}
+		s.pending.sent(n, tx.GasPrice())
+		gapFillMeter.Mark(1)
+		filled++
+	}
+	if filled > 0 {
+		s.lgr.Info("Filled nonce gaps", zap.Uint64("from", from), zap.Uint64("nonce", s.nonce),
+			zap.Int("filled", filled), zap.Int("uncertain", unsure))
+	}
+}
@@ -0,0 +1,116 @@
+package chainload
+
+import (
+	"context"
+	"math/big"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/gochain-io/chainload/mocknode"
+	"github.com/gochain/gochain/v3/common"
+	"github.com/gochain/gochain/v3/core/types"
+	"github.com/gochain/gochain/v3/params"
+)
+
+func TestSender_fillGaps(t *testing.T) {
+	mock, node, accts, cleanup := newTestNode(t, 1e18)
+	defer cleanup()
+	if err := node.unlock(accts[0]); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	s := &Sender{Node: node, rng: rand.New(rand.NewSource(1)), acct: &accts[0], gasPrice: big.NewInt(1),
+		nonceCheck: time.Millisecond}
+	s.setLgr()
+	mock.Pause(true)
+	addr := accts[0].Address
+
+	// Nothing was accepted but 3, which is presumably queued behind the gap.
+	s.nonce = 40
+	s.pending.sent(3, big.NewInt(1))
+	filled := gapFillMeter.Count()
+	s.checkNonces(ctx, false)
+	if n := gapFillMeter.Count() - filled; n != maxGapFill {
+		t.Fatalf("expected %d gaps filled but got %d", maxGapFill, n)
+	}
+	if mock.Pooled(addr, 3) != nil {
+		t.Error("expected accepted nonce 3 to be skipped")
+	}
+	if mock.Pooled(addr, maxGapFill) == nil || mock.Pooled(addr, maxGapFill+1) != nil {
+		t.Errorf("expected nonces up to %d to be filled", maxGapFill)
+	}
+
+	// Pending is now 3, which is refilled since it was evidently not accepted,
+	// then filling resumes after the previous check.
+	s.checkNonces(ctx, false)
+	if n := gapFillMeter.Count() - filled; n != 2*maxGapFill {
+		t.Fatalf("expected %d gaps filled but got %d", 2*maxGapFill, n)
+	}
+	if mock.Pooled(addr, 3) == nil {
+		t.Error("expected nonce 3 to be filled")
+	}
+	if mock.Pooled(addr, 2*maxGapFill-1) == nil || mock.Pooled(addr, 2*maxGapFill) != nil {
+		t.Errorf("expected nonces up to %d to be filled", 2*maxGapFill-1)
+	}
+
+	mock.Pause(false)
+	mock.Mine()
+	if n := mock.Nonce(addr); n != 2*maxGapFill {
+		t.Errorf("expected nonce %d but got %d", 2*maxGapFill, n)
+	}
+}
+
+func TestSender_sendUnsure(t *testing.T) {
+	mock, node, accts, cleanup := newTestNode(t, 1e18)
+	defer cleanup()
+	if err := node.unlock(accts[0]); err != nil {
+		t.Fatal(err)
+	}
+	s := &Sender{Node: node, rng: rand.New(rand.NewSource(1)), acct: &accts[0], gasPrice: big.NewInt(1),
+		amount: 1, recv: []common.Address{{1}}, nonceCheck: time.Millisecond}
+	s.setLgr()
+	addr := accts[0].Address
+
+	// Rejected in transit, which is indistinguishable from a lost response.
+	mock.Inject(mocknode.Fault{Method: "eth_sendRawTransaction", Count: 1, Status: 503})
+	// Cut short the pause after the error.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	s.send(ctx, time.Now())
+	cancel()
+	if s.nonce != 1 {
+		t.Fatalf("expected nonce to advance to 1 but got %d", s.nonce)
+	}
+	if _, ok := s.pending.uncertain[0]; !ok {
+		t.Fatal("expected nonce 0 to be uncertain")
+	}
+
+	// It was not accepted, so the gap is filled.
+	ctx = context.Background()
+	filled := gapFillMeter.Count()
+	s.checkNonces(ctx, false)
+	if n := gapFillMeter.Count() - filled; n != 1 {
+		t.Fatalf("expected 1 gap filled but got %d", n)
+	}
+	mock.Mine()
+	if n := mock.Nonce(addr); n != 1 {
+		t.Fatalf("expected nonce 1 but got %d", n)
+	}
+
+	// Accepted without the sender knowing, so the local nonce lags and is advanced.
+	tx, err := node.SignTx(accts[0], types.NewTransaction(1, addr, new(big.Int), params.TxGas, big.NewInt(1), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Client.SendTransaction(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	synced := nonceSyncMeter.Count()
+	s.checkNonces(ctx, false)
+	if s.nonce != 2 {
+		t.Errorf("expected nonce to advance to 2 but got %d", s.nonce)
+	}
+	if n := nonceSyncMeter.Count() - synced; n != 1 {
+		t.Errorf("expected 1 nonce sync but got %d", n)
+	}
+}
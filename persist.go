@@ -0,0 +1,123 @@
+package chainload
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/gochain/gochain/v3/accounts"
+	"github.com/gochain/gochain/v3/common"
+)
+
+// Roles of persisted accounts.
+const (
+	seederState = "seeder"
+	senderState = "sender"
+)
+
+// storeState is the persisted state of an AccountStore. Nonces and balances are
+// only hints, which are corrected lazily when they turn out to be wrong.
+type storeState struct {
+	Accounts []acctState `json:"accounts"`
+}
+
+type acctState struct {
+	Address common.Address `json:"address"`
+	Role    string         `json:"role"`
+	Node    int            `json:"node"`
+	Nonce   uint64         `json:"nonce"`
+	Balance *big.Int       `json:"balance,omitempty"`
+}
+
+// statePath returns the path of the state file kept next to a keystore directory.
+func statePath(keystoreDir string) string {
+	return filepath.Clean(keystoreDir) + ".state.json"
+}
+
+// load restores the seeds and pools persisted by a previous run. Accounts
+// missing from the keystore are ignored.
+func (a *AccountStore) load() error {
+	b, err := ioutil.ReadFile(a.statePath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read state file: %v", err)
+	}
+	var st storeState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return fmt.Errorf("failed to parse state file %s: %v", a.statePath, err)
+	}
+	byAddr := make(map[common.Address]accounts.Account, len(a.ksAccts))
+	for _, acct := range a.ksAccts {
+		byAddr[acct.Address] = acct
+	}
+	for _, as := range st.Accounts {
+		acct, ok := byAddr[as.Address]
+		if !ok {
+			continue
+		}
+		if _, ok := a.ksAcctsSet[as.Address]; ok {
+			continue
+		}
+		a.ksAcctsSet[as.Address] = struct{}{}
+		a.addrs = append(a.addrs, as.Address)
+		if as.Balance != nil {
+			a.balances[as.Address] = as.Balance
+		}
+		an := acctNonce{Account: &acct, nonce: as.Nonce}
+		switch as.Role {
+		case seederState:
+			a.prevSeeds = append(a.prevSeeds, an)
+		default:
+			pool := a.pools[as.Node]
+			if pool == nil {
+				pool = make(map[common.Address]acctNonce)
+				a.pools[as.Node] = pool
+			}
+			pool[as.Address] = an
+		}
+	}
+	return nil
+}
+
+// Save persists the seeds and pooled accounts, if a state file is configured.
+// Accounts currently assigned to senders are not included.
+func (a *AccountStore) Save() error {
+	if a.statePath == "" {
+		return nil
+	}
+	var st storeState
+	a.acctsMu.RLock()
+	for addr, nonce := range a.seeds {
+		st.Accounts = append(st.Accounts, acctState{Address: addr, Role: seederState, Nonce: nonce, Balance: a.balances[addr]})
+	}
+	for _, an := range a.prevSeeds {
+		st.Accounts = append(st.Accounts, acctState{Address: an.Address, Role: seederState, Nonce: an.nonce, Balance: a.balances[an.Address]})
+	}
+	for node, pool := range a.pools {
+		for addr, an := range pool {
+			st.Accounts = append(st.Accounts, acctState{Address: addr, Role: senderState, Node: node, Nonce: an.nonce, Balance: a.balances[addr]})
+		}
+	}
+	a.acctsMu.RUnlock()
+	sort.Slice(st.Accounts, func(i, j int) bool {
+		if st.Accounts[i].Role != st.Accounts[j].Role {
+			return st.Accounts[i].Role < st.Accounts[j].Role
+		}
+		return st.Accounts[i].Address.Hex() < st.Accounts[j].Address.Hex()
+	})
+
+	b, err := json.MarshalIndent(&st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := a.statePath + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	return os.Rename(tmp, a.statePath)
+}
@@ -0,0 +1,254 @@
+package chainload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gochain/gochain/v3"
+	"github.com/gochain/gochain/v3/common"
+	metrics "github.com/rcrowley/go-metrics"
+	"go.uber.org/zap"
+)
+
+// Read methods.
+const (
+	readBalance  = "balance"  // eth_getBalance
+	readBlock    = "block"    // eth_getBlockByNumber, without txs
+	readBlockTxs = "blockTxs" // eth_getBlockByNumber, with full txs
+	readReceipt  = "receipt"  // eth_getTransactionReceipt
+	readCall     = "call"     // eth_call
+	readLogs     = "logs"     // eth_getLogs
+)
+
+var readMethods = []string{readBalance, readBlock, readBlockTxs, readReceipt, readCall, readLogs}
+
+var readSkippedMeter = metrics.GetOrRegisterMeter("meter/read/skipped", nil)
+
+// errNoReadTarget is returned for reads with nothing to read yet, like receipts
+// before any tx was sent.
+var errNoReadTarget = errors.New("nothing to read")
+
+func readTimer(method string) metrics.Timer {
+	return metrics.GetOrRegisterTimer("timer/read/"+method, nil)
+}
+
+func readErrMeter(method string) metrics.Meter {
+	return metrics.GetOrRegisterMeter("meter/read/"+method+"/err", nil)
+}
+
+// readNoTargetMeter counts reads of method not made for lack of a target.
+func readNoTargetMeter(method string) metrics.Meter {
+	return metrics.GetOrRegisterMeter("meter/read/"+method+"/skipped", nil)
+}
+
+// parseReadMethods parses a csv of read methods.
+func parseReadMethods(csv string) ([]string, error) {
+	var methods []string
+	for _, m := range strings.Split(csv, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		var ok bool
+		for _, rm := range readMethods {
+			if m == rm {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown read method %q: must be one of %v", m, readMethods)
+		}
+		methods = append(methods, m)
+	}
+	return methods, nil
+}
+
+// recentTxs is a fixed size, concurrency safe record of recently sent txs.
+type recentTxs struct {
+	mu     sync.RWMutex
+	hashes []common.Hash
+	idx    int
+	sent   map[common.Hash]time.Time
+}
+
+func newRecentTxs(size int) *recentTxs {
+	return &recentTxs{hashes: make([]common.Hash, 0, size), sent: make(map[common.Hash]time.Time, size)}
+}
+
+func (r *recentTxs) add(hash common.Hash, sent time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.hashes) < cap(r.hashes) {
+		r.hashes = append(r.hashes, hash)
+	} else {
+		delete(r.sent, r.hashes[r.idx])
+		r.hashes[r.idx] = hash
+		r.idx = (r.idx + 1) % len(r.hashes)
+	}
+	r.sent[hash] = sent
+}
+
+// random returns a random recent hash, or false if there are none.
+func (r *recentTxs) random(rng *rand.Rand) (common.Hash, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.hashes) == 0 {
+		return common.Hash{}, false
+	}
+	return r.hashes[rng.Intn(len(r.hashes))], true
+}
+
+// sentAt returns the time hash was sent, or false if it is not recent.
+func (r *recentTxs) sentAt(hash common.Hash) (time.Time, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.sent[hash]
+	return t, ok
+}
+
+var recent = newRecentTxs(10000)
+
+// Reader issues read-only RPC requests, cycling through its methods.
+type Reader struct {
+	*Node
+	Number    int
+	methods   []string
+	contract  *common.Address // Test contract for calls and logs. Optional.
+	logsRange uint64          // Blocks per eth_getLogs request.
+	rng       *rand.Rand
+
+	lgr      *zap.Logger
+	next     int
+	latest   uint64 // Latest block number seen.
+	latestAt time.Time
+}
+
+// Read executes a request for each value received from reqs.
+func (r *Reader) Read(ctx context.Context, reqs <-chan struct{}, done func()) {
+	r.lgr = r.Node.lgr.With(zap.Int("reader", r.Number))
+	defer func() {
+		for range reqs {
+		}
+		done()
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-reqs:
+			if !ok {
+				return
+			}
+			r.read(ctx)
+		}
+	}
+}
+
+func (r *Reader) read(ctx context.Context) {
+	method := r.methods[r.next%len(r.methods)]
+	r.next++
+	t := time.Now()
+	err := r.do(ctx, method)
+	if ctx.Err() != nil {
+		return
+	}
+	if err == errNoReadTarget {
+		readNoTargetMeter(method).Mark(1)
+		return
+	}
+	if err != nil {
+		readErrMeter(method).Mark(1)
+		r.lgr.Debug("Failed to read", zap.String("method", method), zap.Error(err))
+		return
+	}
+	readTimer(method).UpdateSince(t)
+}
+
+func (r *Reader) do(ctx context.Context, method string) error {
+	switch method {
+	case readBalance:
+		addrs := r.AccountStore.NextRecv(r.rng, common.Address{}, 1)
+		if len(addrs) == 0 {
+			return errNoReadTarget
+		}
+		_, err := r.BalanceAt(ctx, addrs[0], nil)
+		return err
+	case readBlock:
+		h, err := r.HeaderByNumber(ctx, nil)
+		if err == nil {
+			r.setLatest(h.Number.Uint64())
+		}
+		return err
+	case readBlockTxs:
+		b, err := r.BlockByNumber(ctx, nil)
+		if err == nil {
+			r.setLatest(b.NumberU64())
+		}
+		return err
+	case readReceipt:
+		hash, ok := recent.random(r.rng)
+		if !ok {
+			return errNoReadTarget
+		}
+		_, err := r.TransactionReceipt(ctx, hash)
+		if err == gochain.NotFound {
+			err = nil
+		}
+		return err
+	case readCall:
+		if r.contract == nil {
+			return errNoReadTarget
+		}
+		data := make([]byte, 32)
+		_, _ = r.rng.Read(data)
+		_, err := r.CallContract(ctx, gochain.CallMsg{To: r.contract, Data: data}, nil)
+		return err
+	case readLogs:
+		latest, err := r.latestBlock(ctx)
+		if err != nil {
+			return err
+		}
+		q := gochain.FilterQuery{ToBlock: new(big.Int).SetUint64(latest)}
+		if latest > r.logsRange {
+			q.FromBlock = new(big.Int).SetUint64(latest - r.logsRange)
+		} else {
+			q.FromBlock = new(big.Int)
+		}
+		if r.contract != nil {
+			q.Addresses = []common.Address{*r.contract}
+		}
+		_, err = r.FilterLogs(ctx, q)
+		return err
+	}
+	return fmt.Errorf("unknown read method: %s", method)
+}
+
+func (r *Reader) setLatest(n uint64) {
+	if n > r.latest {
+		r.latest = n
+		r.latestAt = time.Now()
+	}
+}
+
+// latestBlock returns the latest block number, refreshed at most every 2s.
+func (r *Reader) latestBlock(ctx context.Context) (uint64, error) {
+	if time.Since(r.latestAt) < 2*time.Second {
+		return r.latest, nil
+	}
+	t := time.Now()
+	n, err := r.LatestBlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	latestBlockNumberTimer.UpdateSince(t)
+	r.setLatest(n.Uint64())
+	r.latestAt = time.Now()
+	return r.latest, nil
+}
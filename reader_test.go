@@ -0,0 +1,89 @@
+package chainload
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gochain-io/chainload/mocknode"
+	"github.com/gochain/gochain/v3/common"
+)
+
+func TestReader_Read(t *testing.T) {
+	mock, node, _, cleanup := newTestNode(t, 1e18)
+	defer cleanup()
+	// Nothing to read yet, so not read at all.
+	empty := &Reader{Node: node, methods: []string{readBalance, readCall}, rng: rand.New(rand.NewSource(1))}
+	empty.lgr = node.lgr
+	for _, m := range empty.methods {
+		if err := empty.do(context.Background(), m); err != errNoReadTarget {
+			t.Errorf("%s: expected errNoReadTarget but got: %v", m, err)
+		}
+	}
+	before, skipped := readTimer(readCall).Count(), readNoTargetMeter(readCall).Count()
+	empty.next = 1
+	empty.read(context.Background())
+	if n := readTimer(readCall).Count() - before; n != 0 {
+		t.Errorf("expected no call reads but got %d", n)
+	}
+	if n := readNoTargetMeter(readCall).Count() - skipped; n != 1 {
+		t.Errorf("expected 1 skipped call read but got %d", n)
+	}
+	if _, _, err := node.Next(context.Background(), rand.New(rand.NewSource(1)), node.Number); err != nil {
+		t.Fatal(err)
+	}
+	recent.add(common.Hash{1}, time.Now())
+
+	r := &Reader{Node: node, methods: readMethods, contract: &common.Address{1}, logsRange: 10,
+		rng: rand.New(rand.NewSource(1))}
+	rpcs := map[string]string{
+		readBalance:  "eth_getBalance",
+		readBlock:    "eth_getBlockByNumber",
+		readBlockTxs: "eth_getBlockByNumber",
+		readReceipt:  "eth_getTransactionReceipt",
+		readCall:     "eth_call",
+		readLogs:     "eth_getLogs",
+	}
+	reads := make(map[string]int64)
+	errs := make(map[string]int64)
+	for _, m := range readMethods {
+		reads[m], errs[m] = readTimer(m).Count(), readErrMeter(m).Count()
+	}
+	mock.Inject(mocknode.Fault{Method: "eth_getBalance", Count: 1, Message: "internal error"})
+	mock.Inject(mocknode.Fault{Method: "eth_call", Count: 1, Status: 503})
+
+	const rounds = 3
+	reqs := make(chan struct{}, rounds*len(readMethods))
+	for i := 0; i < cap(reqs); i++ {
+		reqs <- struct{}{}
+	}
+	close(reqs)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	r.Read(context.Background(), reqs, wg.Done)
+	wg.Wait()
+
+	for _, m := range readMethods {
+		var wantErrs int64
+		if m == readBalance || m == readCall {
+			wantErrs = 1
+		}
+		if n := readErrMeter(m).Count() - errs[m]; n != wantErrs {
+			t.Errorf("%s: expected %d errors but got %d", m, wantErrs, n)
+		}
+		if n := readTimer(m).Count() - reads[m]; n != rounds-wantErrs {
+			t.Errorf("%s: expected %d reads but got %d", m, rounds-wantErrs, n)
+		}
+	}
+	for m, rpc := range rpcs {
+		want := rounds
+		if rpc == "eth_getBlockByNumber" {
+			want = 2 * rounds
+		}
+		if n := mock.Calls(rpc); n != want {
+			t.Errorf("%s: expected %d %s calls but got %d", m, want, rpc, n)
+		}
+	}
+}
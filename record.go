@@ -0,0 +1,195 @@
+package chainload
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gochain/gochain/v3/common"
+	"github.com/gochain/gochain/v3/core/types"
+	"github.com/gochain/gochain/v3/rlp"
+)
+
+// Record files start with recordMagic and the chain ID, followed by one
+// record per sent tx:
+//
+//	timestamp int64 (unix nanos) | node uint16 | role uint8 | flags uint8 |
+//	from [20]byte | length uint32 | raw tx [length]byte
+//
+// All integers are big endian.
+var recordMagic = [8]byte{'c', 'h', 'a', 'i', 'n', 'r', 'e', 'c'}
+
+const recordVersion = 1
+
+// Record flags.
+const (
+	recordRejected = 1 << iota // The node returned an error.
+)
+
+// recordRoles maps roles to their encoding.
+var recordRoles = []string{senderRole, seederRole, refundRole, replaceRole, fillRole}
+
+// txRecord is a sent tx.
+type txRecord struct {
+	time     time.Time
+	node     int
+	role     string
+	rejected bool
+	from     common.Address
+	raw      []byte
+}
+
+func (r *txRecord) tx() (*types.Transaction, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(r.raw, tx); err != nil {
+		return nil, fmt.Errorf("failed to decode tx: %v", err)
+	}
+	return tx, nil
+}
+
+// recorder appends sent txs to a file. It is safe for concurrent use.
+type recorder struct {
+	mu  sync.Mutex
+	f   *os.File
+	w   *bufio.Writer
+	err error // First write error.
+}
+
+// newRecorder creates or truncates the file at path, and writes the header.
+func newRecorder(path string, chainID uint64) (*recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create record file: %v", err)
+	}
+	r := &recorder{f: f, w: bufio.NewWriterSize(f, 1<<20)}
+	if err := writeRecordHeader(r.w, chainID); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func writeRecordHeader(w io.Writer, chainID uint64) error {
+	var hdr [17]byte
+	copy(hdr[:], recordMagic[:])
+	hdr[8] = recordVersion
+	binary.BigEndian.PutUint64(hdr[9:], chainID)
+	_, err := w.Write(hdr[:])
+	return err
+}
+
+// record appends tx, sent at t by from.
+func (r *recorder) record(t time.Time, node int, role string, from common.Address, tx *types.Transaction, sendErr error) {
+	raw, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return
+	}
+	r.write(&txRecord{time: t, node: node, role: role, rejected: sendErr != nil, from: from, raw: raw})
+}
+
+// write appends rec.
+func (r *recorder) write(rec *txRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = writeRecord(r.w, rec)
+	}
+}
+
+func writeRecord(w io.Writer, rec *txRecord) error {
+	var hdr [36]byte
+	binary.BigEndian.PutUint64(hdr[0:], uint64(rec.time.UnixNano()))
+	binary.BigEndian.PutUint16(hdr[8:], uint16(rec.node))
+	hdr[10] = 0xff
+	for i, role := range recordRoles {
+		if role == rec.role {
+			hdr[10] = byte(i)
+		}
+	}
+	if rec.rejected {
+		hdr[11] |= recordRejected
+	}
+	copy(hdr[12:32], rec.from[:])
+	binary.BigEndian.PutUint32(hdr[32:], uint32(len(rec.raw)))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(rec.raw)
+	return err
+}
+
+// Close flushes and closes the file, and returns the first error.
+func (r *recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if err := r.f.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// recordTx records tx, sent at t, if recording is enabled. Sends interrupted by
+// cancellation are not recorded.
+func (n *Node) recordTx(ctx context.Context, t time.Time, role string, from common.Address, tx *types.Transaction, err error) {
+	if n.record == nil || ctx.Err() != nil || err == errBreakerOpen {
+		// Not sent, so not part of the stream the node saw.
+		return
+	}
+	n.record.record(t, n.Number, role, from, tx, err)
+}
+
+// recordReader reads a record file.
+type recordReader struct {
+	r       *bufio.Reader
+	chainID uint64
+}
+
+func newRecordReader(r io.Reader) (*recordReader, error) {
+	br := bufio.NewReaderSize(r, 1<<20)
+	var hdr [17]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, fmt.Errorf("failed to read record header: %v", err)
+	}
+	if !bytes.Equal(hdr[:8], recordMagic[:]) {
+		return nil, errors.New("not a chainload record file")
+	}
+	if hdr[8] != recordVersion {
+		return nil, fmt.Errorf("unsupported record version: %d", hdr[8])
+	}
+	return &recordReader{r: br, chainID: binary.BigEndian.Uint64(hdr[9:])}, nil
+}
+
+// next returns the next record, or io.EOF.
+func (rr *recordReader) next() (*txRecord, error) {
+	var hdr [36]byte
+	if _, err := io.ReadFull(rr.r, hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errors.New("truncated record")
+		}
+		return nil, err
+	}
+	rec := &txRecord{
+		time:     time.Unix(0, int64(binary.BigEndian.Uint64(hdr[0:]))),
+		node:     int(binary.BigEndian.Uint16(hdr[8:])),
+		rejected: hdr[11]&recordRejected != 0,
+		raw:      make([]byte, binary.BigEndian.Uint32(hdr[32:])),
+	}
+	if i := int(hdr[10]); i < len(recordRoles) {
+		rec.role = recordRoles[i]
+	}
+	copy(rec.from[:], hdr[12:32])
+	if _, err := io.ReadFull(rr.r, rec.raw); err != nil {
+		return nil, errors.New("truncated record")
+	}
+	return rec, nil
+}
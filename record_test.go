@@ -0,0 +1,137 @@
+package chainload
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gochain-io/chainload/mocknode"
+	"github.com/gochain/gochain/v3/common"
+	"github.com/gochain/gochain/v3/core/types"
+	"github.com/gochain/gochain/v3/rlp"
+	"go.uber.org/zap"
+)
+
+func TestRecord_roundTrip(t *testing.T) {
+	tx := types.NewTransaction(7, common.HexToAddress("0x01"), big.NewInt(10), 21000, big.NewInt(1), []byte{1, 2, 3})
+	recs := []txRecord{
+		{time: time.Unix(0, 1), node: 0, role: senderRole, from: common.HexToAddress("0xaa")},
+		{time: time.Unix(0, 2), node: 3, role: seederRole, rejected: true, from: common.HexToAddress("0xbb")},
+		{time: time.Unix(0, 3), node: 1, role: refundRole, from: common.HexToAddress("0xcc")},
+	}
+	var buf bytes.Buffer
+	if err := writeRecordHeader(&buf, testChainID); err != nil {
+		t.Fatal(err)
+	}
+	for i := range recs {
+		var err error
+		recs[i].raw, err = rlp.EncodeToBytes(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writeRecord(&buf, &recs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rr, err := newRecordReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr.chainID != testChainID {
+		t.Errorf("expected chain ID %d but got %d", testChainID, rr.chainID)
+	}
+	for _, exp := range recs {
+		got, err := rr.next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.time.Equal(exp.time) || got.node != exp.node || got.role != exp.role ||
+			got.rejected != exp.rejected || got.from != exp.from {
+			t.Errorf("expected %+v but got %+v", exp, got)
+		}
+		gotTx, err := got.tx()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gotTx.Hash() != tx.Hash() {
+			t.Errorf("expected tx %s but got %s", tx.Hash().Hex(), gotTx.Hash().Hex())
+		}
+	}
+	if _, err := rr.next(); err != io.EOF {
+		t.Errorf("expected EOF but got: %v", err)
+	}
+}
+
+func TestReplayer_Run(t *testing.T) {
+	const funds = 1000000000
+	_, node, accts, cleanup := newTestNode(t, funds)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "chainload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "run.rec")
+	node.record, err = newRecorder(path, testChainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Record a few txs, the last of which is rejected as a duplicate.
+	ctx := context.Background()
+	from := accts[0]
+	if err := node.unlock(from); err != nil {
+		t.Fatal(err)
+	}
+	for i, nonce := range []uint64{0, 1, 2, 0} {
+		tx, err := node.SignTx(from, types.NewTransaction(nonce, common.HexToAddress("0x01"), big.NewInt(1), 21000, big.NewInt(1), nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = node.sendTx(ctx, senderRole, from.Address, tx)
+		if i < 3 && err != nil {
+			t.Fatal(err)
+		} else if i == 3 && err == nil {
+			t.Fatal("expected duplicate to be rejected")
+		}
+	}
+	// Never sent, so not recorded.
+	tx, err := node.SignTx(from, types.NewTransaction(3, common.HexToAddress("0x01"), big.NewInt(1), 21000, big.NewInt(1), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.recordTx(ctx, time.Now(), senderRole, from.Address, tx, errBreakerOpen)
+	if err := node.record.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replay against a fresh chain from the same genesis.
+	fresh := mocknode.New(mocknode.Config{BlockTime: 50 * time.Millisecond,
+		Alloc: map[common.Address]*big.Int{from.Address: big.NewInt(funds)}})
+	defer fresh.Close()
+	// Records would be sent to the wrong urls.
+	missing := &ReplayConfig{Id: testChainID, UrlsCSV: "http://localhost:1," + fresh.URL, File: path, Speed: 0, Workers: 2}
+	if _, err := missing.NewReplayer(zap.NewNop()); err == nil {
+		t.Error("expected an undialable url to fail")
+	}
+	config := &ReplayConfig{Id: testChainID, UrlsCSV: fresh.URL, File: path, Speed: 0, Workers: 2}
+	r, err := config.NewReplayer(zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	fresh.Mine()
+	if got := fresh.Nonce(from.Address); got != 3 {
+		t.Errorf("expected replayed nonce 3 but got %d", got)
+	}
+}
@@ -0,0 +1,270 @@
+package chainload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ReplayConfig configures a Replayer.
+type ReplayConfig struct {
+	Id      uint64
+	UrlsCSV string
+	File    string  // Record file.
+	Speed   float64 // Timing scale: 2 replays twice as fast. 0 sends as fast as possible.
+	TPS     int     // Fixed send rate, ignoring recorded timing. Optional.
+	Workers int     // Concurrent senders. Txs from the same account are always sent in order.
+	Errors  string  // JSON file of additional error patterns by class. Optional.
+
+	ReportInterval time.Duration
+}
+
+func (c *ReplayConfig) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	oe.AddUint64("id", c.Id)
+	oe.AddString("urls", c.UrlsCSV)
+	oe.AddString("file", c.File)
+	oe.AddFloat64("speed", c.Speed)
+	oe.AddInt("tps", c.TPS)
+	oe.AddInt("workers", c.Workers)
+	oe.AddString("errors", c.Errors)
+	oe.AddDuration("reportInterval", c.ReportInterval)
+	return nil
+}
+
+// Replayer re-sends a recorded stream of txs, preserving their relative
+// timing, or at a fixed rate. Recorded node numbers are mapped onto the replay
+// urls.
+type Replayer struct {
+	config *ReplayConfig
+	lgr    *zap.Logger
+	nodes  []*Node
+}
+
+func (config *ReplayConfig) NewReplayer(lgr *zap.Logger) (*Replayer, error) {
+	if config.Speed < 0 {
+		return nil, fmt.Errorf("illegal speed argument: %v", config.Speed)
+	}
+	if config.ReportInterval <= 0 {
+		config.ReportInterval = 30 * time.Second
+	}
+	if config.TPS < 0 {
+		return nil, fmt.Errorf("illegal TPS argument: %d", config.TPS)
+	}
+	if config.Workers < 1 {
+		return nil, fmt.Errorf("illegal workers argument: %d", config.Workers)
+	}
+	errs, err := newErrClassifier(config.Errors)
+	if err != nil {
+		return nil, err
+	}
+	urls := strings.Split(config.UrlsCSV, ",")
+	// Indexed by url, since records are sent to the url at their node index.
+	var nodes []*Node
+	for i, url := range urls {
+		client, ok := dialNode(lgr, url, config.Id)
+		if !ok {
+			return nil, fmt.Errorf("failed to dial url %d: %s", i, url)
+		}
+		nodes = append(nodes, &Node{
+			lgr:    lgr.With(zap.Int("node", i), zap.String("url", url)),
+			Number: i,
+			Client: &latencyClient{Client: client, node: i},
+			errs:   errs,
+		})
+	}
+	return &Replayer{config: config, lgr: lgr, nodes: nodes}, nil
+}
+
+// replaySummary counts replayed txs by outcome.
+type replaySummary struct {
+	records    int64
+	sent       int64
+	rejected   int64
+	diverged   int64 // Outcome differs from the recording.
+	maxLateNs  int64
+	decodeErrs int64
+}
+
+func (r *replaySummary) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	oe.AddInt64("records", atomic.LoadInt64(&r.records))
+	oe.AddInt64("sent", atomic.LoadInt64(&r.sent))
+	oe.AddInt64("rejected", atomic.LoadInt64(&r.rejected))
+	oe.AddInt64("diverged", atomic.LoadInt64(&r.diverged))
+	oe.AddInt64("decodeErrs", atomic.LoadInt64(&r.decodeErrs))
+	oe.AddDuration("maxLate", time.Duration(atomic.LoadInt64(&r.maxLateNs)))
+	return nil
+}
+
+func (r *replaySummary) late(d time.Duration) {
+	for {
+		max := atomic.LoadInt64(&r.maxLateNs)
+		if int64(d) <= max || atomic.CompareAndSwapInt64(&r.maxLateNs, max, int64(d)) {
+			return
+		}
+	}
+}
+
+func (r *Replayer) Run() error {
+	f, err := os.Open(r.config.File)
+	if err != nil {
+		return fmt.Errorf("failed to open record file: %v", err)
+	}
+	defer f.Close()
+	rr, err := newRecordReader(f)
+	if err != nil {
+		return err
+	}
+	if rr.chainID != r.config.Id {
+		return fmt.Errorf("record file is for chain %d, not %d", rr.chainID, r.config.Id)
+	}
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		select {
+		case sig := <-sigCh:
+			r.lgr.Info("Signal received. Stopping...", zap.String("signal", sig.String()))
+			cancelFn()
+		case <-ctx.Done():
+		}
+	}()
+
+	var sum replaySummary
+	var wg sync.WaitGroup
+	workers := make([]chan replayItem, r.config.Workers)
+	var first time.Time
+	start := time.Now()
+	for i := range workers {
+		ch := make(chan replayItem, 1000)
+		workers[i] = ch
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for it := range ch {
+				if ctx.Err() != nil {
+					continue
+				}
+				if wait := time.Until(it.due); wait > 0 {
+					select {
+					case <-time.After(wait):
+					case <-ctx.Done():
+						continue
+					}
+				} else {
+					sum.late(-wait)
+				}
+				r.send(ctx, it, &sum)
+			}
+		}()
+	}
+
+	stats := NewReporter()
+	var reports Reports
+	var reportsMu sync.Mutex
+	done := make(chan struct{})
+	go func() {
+		report := time.NewTicker(r.config.ReportInterval)
+		defer report.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-report.C:
+				reportsMu.Lock()
+				s := reports.Add(stats.Report())
+				reportsMu.Unlock()
+				r.lgr.Info("Status", zap.Object("status", s), zap.Object("replay", &sum))
+			}
+		}
+	}()
+
+	r.lgr.Info("Replaying", zap.String("file", r.config.File))
+	var readErr error
+loop:
+	for ctx.Err() == nil {
+		rec, err := rr.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			readErr = err
+			break
+		}
+		if first.IsZero() {
+			first = rec.time
+		}
+		i := atomic.AddInt64(&sum.records, 1) - 1
+		var due time.Time // Unscheduled, as fast as possible.
+		if r.config.TPS > 0 {
+			due = start.Add(time.Duration(i) * time.Second / time.Duration(r.config.TPS))
+		} else if r.config.Speed > 0 {
+			due = start.Add(time.Duration(float64(rec.time.Sub(first)) / r.config.Speed))
+		}
+		// Pin each account to a worker, to preserve its nonce order.
+		select {
+		case workers[int(rec.from[len(rec.from)-1])%len(workers)] <- replayItem{rec: rec, due: due}:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	for _, w := range workers {
+		close(w)
+	}
+	wg.Wait()
+	close(done)
+
+	reportsMu.Lock()
+	s := reports.Add(stats.Report())
+	reportsMu.Unlock()
+	r.lgr.Info("Final Status", zap.Object("status", s), zap.Object("replay", &sum),
+		zap.Duration("duration", time.Since(start)))
+	if readErr != nil {
+		return fmt.Errorf("failed to read record file: %v", readErr)
+	}
+	return nil
+}
+
+// replayItem is a record due to be sent.
+type replayItem struct {
+	rec *txRecord
+	due time.Time // Zero if unscheduled.
+}
+
+// send re-sends it via the node it was originally sent to.
+func (r *Replayer) send(ctx context.Context, it replayItem, sum *replaySummary) {
+	rec := it.rec
+	tx, err := rec.tx()
+	if err != nil {
+		atomic.AddInt64(&sum.decodeErrs, 1)
+		r.lgr.Warn("Skipping record", zap.Error(err))
+		return
+	}
+	n := r.nodes[rec.node%len(r.nodes)]
+	err = n.sendTx(ctx, rec.role, rec.from, tx)
+	if ctx.Err() != nil {
+		return
+	}
+	atomic.AddInt64(&sum.sent, 1)
+	if err != nil {
+		atomic.AddInt64(&sum.rejected, 1)
+	} else if !it.due.IsZero() {
+		latencies.get(n.Number, histScheduledSend).record(time.Since(it.due))
+	}
+	if (err != nil) != rec.rejected {
+		atomic.AddInt64(&sum.diverged, 1)
+		n.lgr.Debug("Replayed tx outcome diverged", zap.Stringer("hash", tx.Hash()),
+			zap.Bool("recordedRejected", rec.rejected), zap.Error(err))
+	}
+}
@@ -0,0 +1,187 @@
+package chainload
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// runResults is the machine-readable record of a run, written by -out: the
+// configuration, every interval report, and a summary of the whole run.
+type runResults struct {
+	Version   string                 `json:"version,omitempty"`
+	Config    map[string]interface{} `json:"config"`
+	Start     time.Time              `json:"start"`
+	End       time.Time              `json:"end"`
+	Summary   *runInterval           `json:"summary"`
+	Intervals []*runInterval         `json:"intervals"`
+}
+
+// runInterval holds the statistics of a Report, for output.
+type runInterval struct {
+	Time         time.Time                `json:"time"`
+	Elapsed      float64                  `json:"elapsed"`  // Seconds since the start of the run.
+	Duration     float64                  `json:"duration"` // Seconds.
+	TargetTPS    int                      `json:"targetTps"`
+	Txs          int64                    `json:"txs"`
+	TPS          float64                  `json:"tps"`
+	Confirmed    int64                    `json:"confirmed"`
+	ConfirmedTPS float64                  `json:"confirmedTps"`
+	Unconfirmed  int64                    `json:"unconfirmed"`
+	Skipped      int64                    `json:"skipped"` // Never polled for receipts.
+	Errs         int64                    `json:"errs"`
+	Rejected     int64                    `json:"rejected"` // By open circuit breakers. Not errors.
+	Bytes        int64                    `json:"bytes"`
+	BPS          float64                  `json:"bps"`
+	Seeded       *big.Int                 `json:"seeded"`
+	Spent        *big.Int                 `json:"spent"`
+	Reads        int64                    `json:"reads"`
+	ReadErrs     int64                    `json:"readErrs"`
+	WsNotifs     int64                    `json:"wsNotifs"`
+	WsDropped    int64                    `json:"wsDropped"`
+	WsReconnects int64                    `json:"wsReconnects"`
+	Errors       map[string]int64         `json:"errors"`  // By class.
+	States       map[string]int64         `json:"states"`  // Current count of each state.
+	Latency      map[string]*latencyStats `json:"latency"` // By method.
+	Nodes        []*nodeStats             `json:"nodes"`
+}
+
+// latencyStats are latency percentiles, in milliseconds.
+type latencyStats struct {
+	Count int64   `json:"count"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	P999  float64 `json:"p99.9"`
+	Max   float64 `json:"max"`
+}
+
+func newLatencyStats(h *histogram) *latencyStats {
+	if h == nil {
+		h = newHistogram()
+	}
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	return &latencyStats{
+		Count: h.count,
+		P50:   ms(h.quantile(0.5)),
+		P90:   ms(h.quantile(0.9)),
+		P99:   ms(h.quantile(0.99)),
+		P999:  ms(h.quantile(0.999)),
+		Max:   ms(time.Duration(h.max) * time.Microsecond),
+	}
+}
+
+// nodeStats are the successful sends of a node, and their latency.
+type nodeStats struct {
+	Txs     int64         `json:"txs"`
+	TPS     float64       `json:"tps"`
+	Latency *latencyStats `json:"latency"`
+}
+
+// newRunInterval returns the statistics of r, which ended at now, for a run
+// which started at start against the given number of nodes.
+func newRunInterval(now, start time.Time, target, nodes int, r *Report) *runInterval {
+	secs := r.dur.Seconds()
+	iv := &runInterval{
+		Time:         now.UTC(),
+		Elapsed:      now.Sub(start).Seconds(),
+		Duration:     secs,
+		TargetTPS:    target,
+		Txs:          r.txs,
+		TPS:          r.TPS(),
+		Confirmed:    r.confirmed,
+		ConfirmedTPS: float64(r.confirmed) / secs,
+		Unconfirmed:  r.unconfirmed,
+		Skipped:      r.skipped,
+		Errs:         r.errs,
+		Rejected:     r.rej,
+		Bytes:        r.bytes,
+		BPS:          float64(r.bytes) / secs,
+		Seeded:       addBig(r.seeded, nil),
+		Spent:        addBig(r.spent.total, nil),
+		Reads:        r.reads,
+		ReadErrs:     r.readErrs,
+		WsNotifs:     r.wsNotifs,
+		WsDropped:    r.wsDropped,
+		WsReconnects: r.wsReconnects,
+		Errors:       make(map[string]int64, len(errClasses)),
+		States:       make(map[string]int64, len(stateCounters)),
+		Latency:      make(map[string]*latencyStats, len(histNames)),
+	}
+	for _, c := range errClasses {
+		iv.Errors[string(c)] = r.errClasses[c]
+	}
+	for _, s := range stateCounters {
+		iv.States[s.name] = s.Count()
+	}
+	methods := r.latency.byMethod()
+	for _, m := range histNames {
+		iv.Latency[m] = newLatencyStats(methods[m])
+	}
+	byNode := r.latency.byNode()
+	for i := 0; i < nodes; i++ {
+		h := byNode[i][histSendTx]
+		n := &nodeStats{Latency: newLatencyStats(h)}
+		n.Txs = n.Latency.Count
+		n.TPS = float64(n.Txs) / secs
+		iv.Nodes = append(iv.Nodes, n)
+	}
+	return iv
+}
+
+// newRunResults returns results for a run with config.
+func newRunResults(config zapcore.ObjectMarshaler, start time.Time) *runResults {
+	return &runResults{Config: configFields(config), Start: start.UTC()}
+}
+
+// configFields returns the logged fields of config.
+func configFields(config zapcore.ObjectMarshaler) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	_ = config.MarshalLogObject(enc)
+	return enc.Fields
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// write writes the results to path as JSON.
+func (r *runResults) write(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return fmt.Errorf("failed to write results: %v", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// readRunResults reads results written by a previous run.
+func readRunResults(path string) (*runResults, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results: %v", err)
+	}
+	var r runResults
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse results %s: %v", path, err)
+	}
+	if r.Summary == nil {
+		return nil, fmt.Errorf("results %s have no summary", path)
+	}
+	return &r, nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"math/rand"
 	"time"
 
 	"github.com/gochain/gochain/v3/accounts"
@@ -18,15 +19,22 @@ type Seeder struct {
 	*Node
 	lgr  *zap.Logger
 	acct *accounts.Account
+	rng  *rand.Rand
 
-	nonce uint64
+	nonce     uint64
+	synced    bool     // Whether nonce was checked against the node's pending nonce.
+	maxSeed   *big.Int // Per-request limit. Defaults to the seeder's own estimate when nil.
+	dataGas   uint64   // Most calldata gas of a sender tx, for the estimate.
+	dispensed *big.Int // Total funds sent to senders.
 
 	stateTracker
 }
 
+// SeedReq is a request to seed Addr with Amount.
 type SeedReq struct {
-	Addr common.Address
-	Resp chan<- error
+	Addr   common.Address
+	Amount *big.Int // Amount requested. Capped by the seeder's limit.
+	Resp   chan<- error
 }
 
 func (s *Seeder) Run(ctx context.Context, done func()) {
@@ -38,8 +46,14 @@ func (s *Seeder) Run(ctx context.Context, done func()) {
 	defer s.transition(nil)
 	s.transition(seederSeedState)
 
-	bo := backOff{maxWait: 30 * time.Second, wait: 1 * time.Second, lgr: s.lgr}
-	collect := time.NewTimer(randBetweenDur(5*time.Minute, 10*time.Minute))
+	s.dispensed = new(big.Int)
+	defer func() {
+		s.ReturnSeed(s.acct, s.nonce)
+		s.lgr.Info("Stopping seeder", zapBig("dispensed", s.dispensed))
+	}()
+
+	bo := backOff{maxWait: 30 * time.Second, wait: 1 * time.Second, lgr: s.lgr, rng: s.rng}
+	collect := time.NewTimer(randBetweenDur(s.rng, 5*time.Minute, 10*time.Minute))
 	defer collect.Stop()
 	for {
 		s.transition(seederEnsureFundsState)
@@ -53,12 +67,15 @@ func (s *Seeder) Run(ctx context.Context, done func()) {
 		}) {
 			return
 		}
-		fee := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(randBetween(s.gas, 2*s.gas)))
-		amt := fee.Mul(fee, new(big.Int).SetUint64(1000))
+		limit := s.maxSeed
+		if limit == nil {
+			fee := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(2*s.gas+s.dataGas))
+			limit = fee.Mul(fee, new(big.Int).SetUint64(1000))
+		}
 		// Ensure we have enough funds to seed.
 		if !bo.do(ctx, func() (err error) {
 			var c *big.Int
-			c, err = s.ensureFunds(ctx, s.lgr, amt)
+			c, err = s.ensureFunds(ctx, s.lgr, new(big.Int).Set(limit))
 			if err != nil {
 				err = fmt.Errorf("failed to collect enough to seed: collected %s: %v", c, err)
 			}
@@ -71,8 +88,12 @@ func (s *Seeder) Run(ctx context.Context, done func()) {
 		case <-ctx.Done():
 			return
 		case seed := <-s.SeedCh:
-			// Seed the sender with funds.
-			tx := types.NewTransaction(s.nonce, seed.Addr, amt, randBetween(s.gas, 2*s.gas), gasPrice, nil)
+			// Seed the sender with the requested funds, up to the limit.
+			amt := seed.Amount
+			if amt == nil || amt.Cmp(limit) == 1 {
+				amt = limit
+			}
+			tx := types.NewTransaction(s.nonce, seed.Addr, amt, randBetween(s.rng, s.gas, 2*s.gas), gasPrice, nil)
 			t := time.Now()
 			tx, err := s.SignTx(*s.acct, tx)
 			if err != nil {
@@ -81,17 +102,16 @@ func (s *Seeder) Run(ctx context.Context, done func()) {
 				continue
 			}
 			signTxTimer.UpdateSince(t)
-			t = time.Now()
-			err = s.SendTransaction(ctx, tx)
+			err = s.sendTx(ctx, seederRole, s.acct.Address, tx)
 			if err != nil {
 				if ctx.Err() != nil {
 					return
 				}
-				sendTxErrMeter.Mark(1)
 				s.lgr.Warn("Failed to send seed tx", zap.Error(err))
 				seed.Resp <- err
 				var wait time.Duration
-				if msg := err.Error(); nonceErr(msg) || knownTxErr(msg) {
+				switch s.errs.classify(err) {
+				case errClassNonceTooLow, errClassKnownTx, errClassReplaceUnderpriced:
 					old := s.nonce
 					s.transition(seederUpdateNonceState)
 					if !bo.doTimed(ctx, pendingNonceAtTimer, func() (err error) {
@@ -104,15 +124,15 @@ func (s *Seeder) Run(ctx context.Context, done func()) {
 						return
 					}
 					s.lgr.Info("Updated nonce", zap.Uint64("nonce", s.nonce), zap.Uint64("old", old))
-				} else if msg == "transaction pool limit reached" {
-					wait = randBetweenDur(5*time.Second, 30*time.Second)
-				} else if lowFundsErr(msg) {
+				case errClassPoolLimit:
+					wait = randBetweenDur(s.rng, 5*time.Second, 30*time.Second)
+				case errClassLowFunds:
 					s.transition(seederCollectState)
-					if c, err := s.collect(ctx, amt); err != nil {
+					if c, err := s.collect(ctx, new(big.Int).Set(limit)); err != nil {
 						s.lgr.Warn("Refund collection failed", zapBig("collected", c), zap.Error(err))
 					}
-				} else {
-					wait = randBetweenDur(5*time.Second, 30*time.Second)
+				default:
+					wait = randBetweenDur(s.rng, 5*time.Second, 30*time.Second)
 				}
 				if wait != 0 {
 					s.lgr.Info("Pausing seeder", zap.Duration("wait", wait), zap.Error(err))
@@ -123,17 +143,21 @@ func (s *Seeder) Run(ctx context.Context, done func()) {
 					}
 				}
 			} else {
-				sendTxTimer.UpdateSince(t)
 				s.nonce++
+				s.dispensed.Add(s.dispensed, amt)
+				seededFunds.Add(amt)
+				s.lgr.Info("Seeded account", zap.Stringer("recipient", seed.Addr), zapBig("amount", amt),
+					zapBig("requested", seed.Amount), zapBig("dispensed", s.dispensed))
 				seed.Resp <- nil
 			}
 		case <-collect.C:
 			s.transition(seederCollectState)
 			// Collect more funds for a while.
-			collectCtx, _ := context.WithTimeout(ctx, 10*time.Second)
-			if c, err := s.collect(collectCtx, amt); err != nil {
+			collectCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			if c, err := s.collect(collectCtx, new(big.Int).Set(limit)); err != nil {
 				s.lgr.Warn("Refund collection failed", zapBig("collected", c), zap.Error(err))
 			}
+			cancel()
 			s.transition(seederSeedState)
 		}
 	}
@@ -141,7 +165,7 @@ func (s *Seeder) Run(ctx context.Context, done func()) {
 }
 
 func (s *Seeder) ensureFunds(ctx context.Context, lgr *zap.Logger, ensure *big.Int) (*big.Int, error) {
-	bo := backOff{maxWait: 30 * time.Second, wait: 1 * time.Second, lgr: s.lgr}
+	bo := backOff{maxWait: 30 * time.Second, wait: 1 * time.Second, lgr: s.lgr, rng: s.rng}
 	var bal *big.Int
 	if !bo.doTimed(ctx, pendingBalanceAtTimer, func() (err error) {
 		bal, err = s.PendingBalanceAt(ctx, s.acct.Address)
@@ -152,19 +176,10 @@ func (s *Seeder) ensureFunds(ctx context.Context, lgr *zap.Logger, ensure *big.I
 	}) {
 		return nil, ctx.Err()
 	}
+	s.noteBalance(s.acct.Address, bal)
 	lgr.Info("Got seeder balance", zapBig("balance", bal))
-	if s.nonce == 0 {
-		old := s.nonce
-		if !bo.doTimed(ctx, pendingNonceAtTimer, func() (err error) {
-			s.nonce, err = s.PendingNonceAt(ctx, s.acct.Address)
-			if err != nil {
-				err = fmt.Errorf("failed to get nonce: %v", err)
-			}
-			return
-		}) {
-			return nil, ctx.Err()
-		}
-		lgr.Info("Updated nonce", zap.Uint64("nonce", s.nonce), zap.Uint64("old", old))
+	if !s.syncNonce(ctx, &bo, lgr) {
+		return nil, ctx.Err()
 	}
 	if bal.Cmp(ensure) == -1 {
 		defer s.transition(s.transition(seederCollectState))
@@ -173,10 +188,41 @@ func (s *Seeder) ensureFunds(ctx context.Context, lgr *zap.Logger, ensure *big.I
 	return nil, nil
 }
 
+// syncNonce sets the nonce from the node's pending nonce the first time it is
+// called. A persisted nonce is not trusted, since one which is too high, e.g.
+// after a chain reset, is accepted into the queue but never mined. It returns
+// false if ctx is done first.
+func (s *Seeder) syncNonce(ctx context.Context, bo *backOff, lgr *zap.Logger) bool {
+	if s.synced {
+		return true
+	}
+	old := s.nonce
+	if !bo.doTimed(ctx, pendingNonceAtTimer, func() (err error) {
+		s.nonce, err = s.PendingNonceAt(ctx, s.acct.Address)
+		if err != nil {
+			err = fmt.Errorf("failed to get nonce: %v", err)
+		}
+		return
+	}) {
+		return false
+	}
+	s.synced = true
+	if old != 0 && old != s.nonce {
+		lgr.Warn("Corrected known nonce", zap.Uint64("known", old), zap.Uint64("pending", s.nonce))
+	} else {
+		lgr.Info("Updated nonce", zap.Uint64("nonce", s.nonce), zap.Uint64("old", old))
+	}
+	return true
+}
+
+// maxEmptyRefunds is the number of consecutive empty accounts after which
+// collection gives up.
+const maxEmptyRefunds = 100
+
 func (s *Seeder) collect(ctx context.Context, amount *big.Int) (*big.Int, error) {
 	collected := new(big.Int)
 	refundNextAcct := func() (*big.Int, error) {
-		acct, nonce, err := s.Next(ctx, s.Number)
+		acct, nonce, err := s.Next(ctx, s.rng, s.Number)
 		if err != nil {
 			return nil, err
 		}
@@ -190,17 +236,22 @@ func (s *Seeder) collect(ctx context.Context, amount *big.Int) (*big.Int, error)
 				s.Return(acct, s.Number, nonce)
 				return nil, err
 			}
-			pendingBalanceAtTimer.UpdateSince(t)
+			pendingNonceAtTimer.UpdateSince(t)
 		}
-		c, err := s.refund(ctx, *acct, nonce, s.acct.Address)
-		if err != nil {
+		c, err := s.refund(ctx, s.rng, *acct, nonce, s.acct.Address)
+		if err != nil || c == nil {
+			// Nothing sent.
 			s.Return(acct, s.Number, nonce)
 			return nil, err
 		}
 		s.Return(acct, s.Number, nonce+1)
 		return c, nil
 	}
-	for collected.Cmp(amount) == -1 && ctx.Err() != nil {
+	var empty int // Consecutive accounts with nothing to refund.
+	for collected.Cmp(amount) == -1 && ctx.Err() == nil {
+		if empty >= maxEmptyRefunds {
+			return collected, errors.New("no funds left to collect")
+		}
 		c, err := refundNextAcct()
 		if err != nil {
 			if ctx.Err() != nil {
@@ -213,7 +264,13 @@ func (s *Seeder) collect(ctx context.Context, amount *big.Int) (*big.Int, error)
 			case <-ctx.Done():
 				return collected, ctx.Err()
 			}
+			continue
+		}
+		if c == nil {
+			empty++
+			continue
 		}
+		empty = 0
 		collected = collected.Add(collected, c)
 	}
 	return collected, nil
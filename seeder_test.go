@@ -0,0 +1,65 @@
+package chainload
+
+import (
+	"context"
+	"math/big"
+	"math/rand"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestSeeder_collect(t *testing.T) {
+	const funds = 1e15
+	mock, node, accts, cleanup := newTestNode(t, 0, funds, funds, funds)
+	defer cleanup()
+	acct, _, err := node.NextSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acct.Address != accts[0].Address {
+		t.Fatalf("expected seeder %s but got %s", accts[0].Address.Hex(), acct.Address.Hex())
+	}
+	s := &Seeder{Node: node, lgr: zap.NewNop(), acct: acct, rng: rand.New(rand.NewSource(1))}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	amount := big.NewInt(2 * funds)
+	collected, err := s.collect(ctx, new(big.Int).Set(amount))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if collected.Cmp(amount) < 0 {
+		t.Fatalf("expected to collect %s but got %s", amount, collected)
+	}
+	mock.Mine()
+	if bal := mock.Balance(acct.Address); bal.Cmp(collected) != 0 {
+		t.Errorf("expected seeder balance %s but got %s", collected, bal)
+	}
+
+	// Nothing left.
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	collected, err = s.collect(ctx, big.NewInt(funds))
+	if err == nil {
+		t.Errorf("expected error with nothing left to collect, but collected %s", collected)
+	}
+}
+
+func TestSeeder_ensureFundsStaleNonce(t *testing.T) {
+	_, node, _, cleanup := newTestNode(t, 1e18)
+	defer cleanup()
+	acct, _, err := node.NextSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Persisted before a chain reset, so too high.
+	s := &Seeder{Node: node, lgr: zap.NewNop(), acct: acct, rng: rand.New(rand.NewSource(1)), nonce: 7}
+	if _, err := s.ensureFunds(context.Background(), s.lgr, big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+	if s.nonce != 0 {
+		t.Errorf("expected nonce 0 from the node but got %d", s.nonce)
+	}
+}
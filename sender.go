@@ -23,12 +23,30 @@ type Sender struct {
 	cycle     time.Duration
 	Number    int
 	RateLimit time.Duration
+	rng       *rand.Rand
 
 	acct     *accounts.Account
 	recv     []common.Address
 	nonce    uint64
 	gasPrice *big.Int
 
+	nonceCheck time.Duration // How often to check for nonce gaps and stuck txs. Optional.
+	stuckAfter time.Duration // Replace pending txs after the confirmed nonce stops advancing. Optional.
+	priceBump  uint64        // Minimum replacement gas price increase, in percent.
+	pending    pendingTxs
+
+	contract    *common.Address // Test contract. Optional.
+	contractTxs float64         // Fraction of txs which call the contract.
+
+	invalid       float64  // Fraction of txs which are deliberately invalid.
+	invalidKinds  []string // Kinds of invalid txs to send.
+	lastTx        *types.Transaction
+	blockGasLimit uint64
+
+	data *dataDist // Random calldata. Optional.
+
+	ready func() // Called once the first account is assigned and funded. Optional.
+
 	stateTracker
 }
 
@@ -38,7 +56,7 @@ func (s *Sender) assignAcct(ctx context.Context) {
 	var old *accounts.Account
 	if s.acct != nil {
 		old = s.acct
-		amount, err := s.refund(ctx, *s.acct, s.nonce, *s.AccountStore.RandSeed())
+		amount, err := s.refund(ctx, s.rng, *s.acct, s.nonce, *s.AccountStore.RandSeed(s.rng))
 		if ctx.Err() != nil {
 			return
 		}
@@ -50,10 +68,11 @@ func (s *Sender) assignAcct(ctx context.Context) {
 		}
 		s.AccountStore.Return(s.acct, s.Node.Number, s.nonce)
 	}
-	bo := backOff{maxWait: 30 * time.Second, wait: 1 * time.Second, lgr: s.lgr}
+	bo := backOff{maxWait: 30 * time.Second, wait: 1 * time.Second, lgr: s.lgr, rng: s.rng}
 
+	s.pending.reset()
 	if !bo.do(ctx, func() (err error) {
-		s.acct, s.nonce, err = s.AccountStore.Next(ctx, s.Node.Number)
+		s.acct, s.nonce, err = s.AccountStore.Next(ctx, s.rng, s.Node.Number)
 		s.setLgr()
 		if err != nil {
 			err = fmt.Errorf("failed to assign sender account: %v", err)
@@ -75,6 +94,9 @@ func (s *Sender) assignAcct(ctx context.Context) {
 			return
 		}
 	} else {
+		// Known nonces are checked, since one which is too high is accepted
+		// into the queue but never mined.
+		known := s.nonce
 		if !bo.doTimed(ctx, pendingNonceAtTimer, func() (err error) {
 			s.nonce, err = s.Client.PendingNonceAt(ctx, s.acct.Address)
 			if err != nil {
@@ -84,6 +106,9 @@ func (s *Sender) assignAcct(ctx context.Context) {
 		}) {
 			return
 		}
+		if known != 0 && known != s.nonce {
+			s.lgr.Warn("Corrected known nonce", zap.Uint64("known", known), zap.Uint64("pending", s.nonce))
+		}
 	}
 
 	var bal *big.Int
@@ -96,13 +121,19 @@ func (s *Sender) assignAcct(ctx context.Context) {
 	}) {
 		return
 	}
+	s.noteBalance(s.acct.Address, bal)
 	if old != nil {
 		s.lgr.Info("Changed account", zapBig("balance", bal), zap.Stringer("old", old.Address))
 	} else {
 		s.lgr.Info("Assigned account", zapBig("balance", bal))
 	}
 
-	fee := new(big.Int).Mul(s.gasPrice, new(big.Int).SetUint64(s.gas))
+	gas := s.gas
+	if s.data != nil {
+		// Enough for the largest calldata.
+		gas += s.data.maxGas()
+	}
+	fee := new(big.Int).Mul(s.gasPrice, new(big.Int).SetUint64(gas))
 	need := fee.Mul(fee, new(big.Int).SetUint64(1000))
 	if bal.Cmp(need) == -1 {
 		diff := new(big.Int).Sub(need, bal)
@@ -124,7 +155,7 @@ func (s *Sender) assignAcct(ctx context.Context) {
 	}
 
 	if !bo.do(ctx, func() error {
-		s.recv = s.AccountStore.NextRecv(s.acct.Address, rand.Intn(10)+1)
+		s.recv = s.AccountStore.NextRecv(s.rng, s.acct.Address, s.rng.Intn(10)+1)
 		if len(s.recv) == 0 {
 			return errors.New("failed to assign sender receivers")
 		}
@@ -161,9 +192,9 @@ func (s *Sender) requestSeed(ctx context.Context, amount *big.Int) error {
 	case <-ctx.Done():
 		return ctx.Err()
 	case s.SeedCh <- SeedReq{
-		//TODO use amount
-		Addr: s.acct.Address,
-		Resp: resp,
+		Addr:   s.acct.Address,
+		Amount: amount,
+		Resp:   resp,
 	}:
 	}
 	select {
@@ -183,7 +214,9 @@ func (s *Sender) setLgr() {
 	}
 }
 
-func (s *Sender) Send(ctx context.Context, txs <-chan struct{}, done func()) {
+// Send sends a tx for each time received from txs, which is when it was
+// scheduled, or zero if it was not.
+func (s *Sender) Send(ctx context.Context, txs <-chan time.Time, done func()) {
 	s.setLgr()
 	defer func() {
 		for range txs {
@@ -201,12 +234,21 @@ func (s *Sender) Send(ctx context.Context, txs <-chan struct{}, done func()) {
 	if ctx.Err() != nil {
 		return
 	}
+	if s.ready != nil {
+		s.ready()
+	}
 	s.transition(senderSendState)
 
-	newAcct := time.NewTimer(randBetweenDur(s.cycle, 2*s.cycle))
+	newAcct := time.NewTimer(randBetweenDur(s.rng, s.cycle, 2*s.cycle))
 	defer newAcct.Stop()
-	updateGas := time.NewTimer(randBetweenDur(time.Minute, 2*time.Minute))
+	updateGas := time.NewTimer(randBetweenDur(s.rng, time.Minute, 2*time.Minute))
 	defer newAcct.Stop()
+	var checkNonces <-chan time.Time
+	if s.nonceCheck > 0 {
+		t := time.NewTicker(s.nonceCheck)
+		defer t.Stop()
+		checkNonces = t.C
+	}
 	for {
 		if ctx.Err() != nil {
 			return
@@ -222,14 +264,43 @@ func (s *Sender) Send(ctx context.Context, txs <-chan struct{}, done func()) {
 			s.transition(senderUpdateGasState)
 			s.updateGasPrice(ctx)
 			s.transition(senderSendState)
-		case <-txs:
-			s.send(ctx)
+		case <-checkNonces:
+			s.transition(senderCheckNoncesState)
+			s.checkNonces(ctx, false)
+			s.transition(senderSendState)
+		case sched := <-txs:
+			if s.invalid > 0 && s.rng.Float64() < s.invalid {
+				s.sendInvalid(ctx)
+				continue
+			}
+			s.send(ctx, sched)
 		}
 	}
 }
 
+// reclaim refunds the balance of the current account to a seeder and returns
+// the account to the AccountStore, even if the refund fails, so that its funds
+// are not lost to later runs. It must only be called after Send returns.
+func (s *Sender) reclaim(ctx context.Context) (*big.Int, error) {
+	defer func() { s.AccountStore.Return(s.acct, s.Node.Number, s.nonce) }()
+	seed := s.AccountStore.RandSeed(s.rng)
+	if seed == nil {
+		return nil, errors.New("no seeder available")
+	}
+	amount, err := s.refund(ctx, s.rng, *s.acct, s.nonce, *seed)
+	if err != nil {
+		s.lgr.Warn("Failed to reclaim account funds", zap.Error(err))
+		return nil, err
+	}
+	if amount != nil {
+		s.nonce++
+		s.lgr.Info("Reclaimed account funds", zapBig("amount", amount))
+	}
+	return amount, nil
+}
+
 func (s *Sender) updateGasPrice(ctx context.Context) {
-	bo := backOff{maxWait: 30 * time.Second, wait: 1 * time.Second, lgr: s.lgr}
+	bo := backOff{maxWait: 30 * time.Second, wait: 1 * time.Second, lgr: s.lgr, rng: s.rng}
 	_ = bo.doTimed(ctx, suggestGasPriceTimer, func() (err error) {
 		s.gasPrice, err = s.Client.SuggestGasPrice(ctx)
 		if err != nil {
@@ -239,15 +310,28 @@ func (s *Sender) updateGasPrice(ctx context.Context) {
 	})
 }
 
-func (s *Sender) send(ctx context.Context) {
+func (s *Sender) send(ctx context.Context, sched time.Time) {
 	recv := s.recv[int(s.nonce)%len(s.recv)]
 	gp := s.gasPrice.Uint64()
-	if rand.Intn(2) == 0 {
-		gp = randBetween(gp, gp*2)
+	if s.rng.Intn(2) == 0 {
+		gp = randBetween(s.rng, gp, gp*2)
 	}
-	amount := new(big.Int).SetUint64(randBetween(s.amount, 2*s.amount))
-	tx := types.NewTransaction(s.nonce, recv, amount, randBetween(s.gas, 2*s.gas), new(big.Int).SetUint64(gp), nil)
+	amount := new(big.Int).SetUint64(randBetween(s.rng, s.amount, 2*s.amount))
+	gas := randBetween(s.rng, s.gas, 2*s.gas)
+	var data []byte
 	t := time.Now()
+	if s.contract != nil && s.rng.Float64() < s.contractTxs {
+		// Timestamped, so that subscribers can measure the delay of the log.
+		recv, amount, data = *s.contract, new(big.Int), contractCallData(t)
+		if gas < testContractCallGas {
+			gas = testContractCallGas
+		}
+	} else if s.data != nil {
+		var dataGas uint64
+		data, dataGas = s.data.data(s.rng)
+		gas += dataGas
+	}
+	tx := types.NewTransaction(s.nonce, recv, amount, gas, new(big.Int).SetUint64(gp), data)
 	tx, err := s.AccountStore.SignTx(*s.acct, tx)
 	if err != nil {
 		s.lgr.Warn("Failed to sign tx", zap.Error(err))
@@ -257,10 +341,15 @@ func (s *Sender) send(ctx context.Context) {
 		return
 	}
 	signTxTimer.UpdateSince(t)
-	t = time.Now()
-	err = s.Client.SendTransaction(ctx, tx)
+	err = s.sendTx(ctx, senderRole, s.acct.Address, tx)
 	if err == nil {
-		sendTxTimer.UpdateSince(t)
+		if !sched.IsZero() {
+			latencies.get(s.Node.Number, histScheduledSend).record(time.Since(sched))
+		}
+		s.lastTx = tx
+		if s.nonceCheck > 0 {
+			s.pending.sent(s.nonce, tx.GasPrice())
+		}
 		s.nonce++
 
 		select {
@@ -274,12 +363,12 @@ func (s *Sender) send(ctx context.Context) {
 	if ctx.Err() != nil {
 		return
 	}
-	sendTxErrMeter.Mark(1)
 	var wait time.Duration
-	if msg := err.Error(); nonceErr(msg) {
+	switch class := s.errs.classify(err); class {
+	case errClassNonceTooLow, errClassReplaceUnderpriced:
 		s.lgr.Warn("Failed to send - updating nonce", zap.Error(err))
 		old := s.nonce
-		bo := backOff{maxWait: 30 * time.Second, wait: 1 * time.Second, lgr: s.lgr}
+		bo := backOff{maxWait: 30 * time.Second, wait: 1 * time.Second, lgr: s.lgr, rng: s.rng}
 		if !bo.doTimed(ctx, pendingNonceAtTimer, func() (err error) {
 			s.nonce, err = s.Client.PendingNonceAt(ctx, s.acct.Address)
 			if err != nil {
@@ -290,17 +379,28 @@ func (s *Sender) send(ctx context.Context) {
 			return
 		}
 		s.lgr.Info("Updated nonce", zap.Uint64("nonce", s.nonce), zap.Uint64("old", old))
+		if class == errClassReplaceUnderpriced && s.nonceCheck > 0 && s.stuckAfter > 0 {
+			// A pending tx is blocking this nonce, so replace the lowest instead of abandoning the account.
+			s.transition(senderCheckNoncesState)
+			s.checkStuck(ctx, true)
+			s.transition(senderSendState)
+		}
 		return
-	} else if msg == "transaction pool limit reached" {
-		wait = randBetweenDur(5*time.Second, 2*time.Minute)
-	} else if knownTxErr(msg) || lowFundsErr(msg) {
+	case errClassPoolLimit:
+		wait = randBetweenDur(s.rng, 5*time.Second, 2*time.Minute)
+	case errClassKnownTx, errClassLowFunds:
 		s.lgr.Info("Abandoning account", zap.Error(err))
 		s.transition(senderAssignState)
 		s.assignAcct(ctx)
 		s.transition(senderSendState)
 		return
-	} else {
-		wait = randBetweenDur(5*time.Second, 30*time.Second)
+	default:
+		if class.transport() && s.nonceCheck > 0 {
+			// The tx may have been accepted anyway. Move on, and fill the gap later if it wasn't.
+			s.pending.unsure(s.nonce)
+			s.nonce++
+		}
+		wait = randBetweenDur(s.rng, 5*time.Second, 30*time.Second)
 	}
 	if wait == 0 {
 		s.lgr.Warn("Failed to send", zap.Error(err))
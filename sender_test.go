@@ -0,0 +1,148 @@
+package chainload
+
+import (
+	"context"
+	"math/big"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gochain-io/chainload/mocknode"
+	"github.com/gochain/gochain/v3/common"
+)
+
+func TestSender_assignAcct(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for blocks")
+	}
+	mock, node, _, cleanup := newTestNode(t, 1e18)
+	defer cleanup()
+	acct, _, err := node.NextSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	seeder := &Seeder{Node: node, acct: acct, rng: rand.New(rand.NewSource(1))}
+	go seeder.Run(ctx, wg.Done)
+	defer wg.Wait()
+	defer cancel()
+
+	// Seeded so that the replacement is a new account, rather than the refunded one.
+	s := &Sender{Node: node, rng: rand.New(rand.NewSource(3)), gasPrice: big.NewInt(1), amount: 1}
+	s.setLgr()
+	// Retried with back off.
+	mock.Inject(mocknode.Fault{Method: "eth_getBalance", Count: 1, Status: 503})
+	s.assignAcct(ctx)
+	if s.acct == nil {
+		t.Fatal("expected account")
+	}
+	if len(s.recv) == 0 {
+		t.Error("expected receivers")
+	}
+	need := big.NewInt(1000 * 21000)
+	if bal := mock.Balance(s.acct.Address); bal.Cmp(need) < 0 {
+		t.Errorf("expected sender to be seeded with %s but got %s", need, bal)
+	}
+
+	// Replacing refunds the old account.
+	old := *s.acct
+	s.assignAcct(ctx)
+	if s.acct == nil || s.acct.Address == old.Address {
+		t.Fatal("expected new account")
+	}
+	mock.Mine()
+	if n := mock.Nonce(old.Address); n != 1 {
+		t.Errorf("expected old account to be refunded, but nonce is %d", n)
+	}
+	if bal := mock.Balance(old.Address); bal.Cmp(need) >= 0 {
+		t.Errorf("expected old account to be refunded, but balance is %s", bal)
+	}
+}
+
+func TestSender_reclaimFailed(t *testing.T) {
+	mock, node, accts, cleanup := newTestNode(t, 1e18, 1e18)
+	defer cleanup()
+	node.ReturnSeed(&accts[1], 0)
+	if err := node.unlock(accts[0]); err != nil {
+		t.Fatal(err)
+	}
+	s := &Sender{Node: node, rng: rand.New(rand.NewSource(1)), acct: &accts[0], nonce: 0, gasPrice: big.NewInt(1)}
+	s.setLgr()
+
+	mock.Inject(mocknode.Fault{Method: "eth_sendRawTransaction", Message: "internal error"})
+	if _, err := s.reclaim(context.Background()); err == nil {
+		t.Fatal("expected refund to fail")
+	}
+	// Returned with the unchanged nonce, so it is saved for the next run.
+	an, ok := node.pools[node.Number][accts[0].Address]
+	if !ok {
+		t.Fatal("expected account to be returned")
+	}
+	if an.nonce != 0 {
+		t.Errorf("expected nonce 0 but got %d", an.nonce)
+	}
+}
+
+func TestSender_assignAcctKnownNonce(t *testing.T) {
+	_, node, _, cleanup := newTestNode(t, 1e18, 1e18)
+	defer cleanup()
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(1))
+	acct, _, err := node.Next(ctx, rng, node.Number)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := node.Next(ctx, rng, node.Number); err != nil {
+		t.Fatal(err)
+	}
+	// Too high, so txs would be queued but never mined.
+	node.Return(acct, node.Number, 5)
+	seed := int64(0)
+	for ; rand.New(rand.NewSource(seed)).Intn(2) != 0; seed++ {
+		// Until the sender takes from the pool.
+	}
+
+	s := &Sender{Node: node, rng: rand.New(rand.NewSource(seed)), gasPrice: big.NewInt(1), amount: 1}
+	s.setLgr()
+	s.assignAcct(ctx)
+	if s.acct == nil || s.acct.Address != acct.Address {
+		t.Fatalf("expected pooled account %s", acct.Address.Hex())
+	}
+	if s.nonce != 0 {
+		t.Errorf("expected nonce to be corrected to 0 but got %d", s.nonce)
+	}
+}
+
+func TestSender_sendUnscheduled(t *testing.T) {
+	_, node, accts, cleanup := newTestNode(t, 1e18)
+	defer cleanup()
+	if err := node.unlock(accts[0]); err != nil {
+		t.Fatal(err)
+	}
+	s := &Sender{Node: node, rng: rand.New(rand.NewSource(1)), acct: &accts[0], gasPrice: big.NewInt(1),
+		amount: 1, recv: []common.Address{{1}}}
+	s.setLgr()
+	scheduled := func() int64 {
+		if h := latencies.snapshot()[histKey{node: node.Number, method: histScheduledSend}]; h != nil {
+			return h.count
+		}
+		return 0
+	}
+	// Sent during start-up, so not measured.
+	before := scheduled()
+	s.send(context.Background(), time.Time{})
+	if s.lastTx == nil {
+		t.Fatal("expected a tx to be sent")
+	}
+	if n := scheduled() - before; n != 0 {
+		t.Errorf("expected no scheduled latency but got %d", n)
+	}
+	s.send(context.Background(), time.Now())
+	if n := scheduled() - before; n != 1 {
+		t.Errorf("expected 1 scheduled latency but got %d", n)
+	}
+}
@@ -0,0 +1,170 @@
+package chainload
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// SLO holds the pass criteria of a run. Zero values disable each check.
+type SLO struct {
+	MinTPS       float64       // Minimum achieved TPS, as a fraction of the target.
+	MaxErrorRate float64       // Maximum fraction of failed sends.
+	MaxP99       time.Duration // Maximum p99 send latency.
+	MaxInclusion time.Duration // Maximum p99 inclusion latency. Requires receipt polling.
+	NoDropped    bool          // Fail if any sent tx was never confirmed. Requires receipt polling.
+	Intervals    bool          // Also check every interval report, rather than only the whole run.
+}
+
+func (s *SLO) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	oe.AddFloat64("minTps", s.MinTPS)
+	oe.AddFloat64("maxErrorRate", s.MaxErrorRate)
+	oe.AddDuration("maxP99", s.MaxP99)
+	oe.AddDuration("maxInclusion", s.MaxInclusion)
+	oe.AddBool("noDropped", s.NoDropped)
+	oe.AddBool("intervals", s.Intervals)
+	return nil
+}
+
+// enabled returns true if any criterion is set.
+func (s *SLO) enabled() bool {
+	return s.MinTPS > 0 || s.MaxErrorRate > 0 || s.MaxP99 > 0 || s.MaxInclusion > 0 || s.NoDropped
+}
+
+// needsReceipts returns true if any criterion is measured by polling receipts.
+func (s *SLO) needsReceipts() bool {
+	return s.MaxInclusion > 0 || s.NoDropped
+}
+
+// sloCheck is the outcome of a single criterion.
+type sloCheck struct {
+	name      string
+	want, got string
+	pass      bool
+	failed    int // Failed interval reports.
+	intervals int // Checked interval reports.
+}
+
+func (c *sloCheck) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	oe.AddBool("pass", c.pass)
+	oe.AddString("want", c.want)
+	oe.AddString("got", c.got)
+	if c.intervals > 0 {
+		oe.AddString("intervals", fmt.Sprintf("%d/%d failed", c.failed, c.intervals))
+	}
+	return nil
+}
+
+// check checks r, over which txs were sent for dur, against each enabled
+// criterion.
+func (s *SLO) check(r *Report, dur time.Duration, target int) []*sloCheck {
+	var cs []*sloCheck
+	add := func(name, want, got string, pass bool) {
+		cs = append(cs, &sloCheck{name: name, want: want, got: got, pass: pass})
+	}
+	if s.MinTPS > 0 && target > 0 {
+		want := s.MinTPS * float64(target)
+		tps := float64(r.txs) / dur.Seconds()
+		add("minTps", fmt.Sprintf(">= %.1f", want), fmt.Sprintf("%.1f", tps), tps >= want)
+	}
+	if s.MaxErrorRate > 0 {
+		var rate float64
+		if n := r.txs + r.errs; n > 0 {
+			rate = float64(r.errs) / float64(n)
+		}
+		add("maxErrorRate", fmt.Sprintf("<= %g", s.MaxErrorRate), fmt.Sprintf("%.4g", rate), rate <= s.MaxErrorRate)
+	}
+	methods := r.latency.byMethod()
+	p99 := func(method string) time.Duration {
+		if h := methods[method]; h != nil {
+			return h.quantile(0.99)
+		}
+		return 0
+	}
+	if s.MaxP99 > 0 {
+		got := p99(histSendTx)
+		add("maxP99", "<= "+s.MaxP99.String(), got.String(), got <= s.MaxP99)
+	}
+	if s.MaxInclusion > 0 {
+		got := p99(histInclusion)
+		add("maxInclusion", "<= "+s.MaxInclusion.String(), got.String(), got <= s.MaxInclusion)
+	}
+	if s.NoDropped {
+		// Txs never polled may have been dropped too.
+		got := fmt.Sprint(r.unconfirmed + r.skipped)
+		if r.skipped > 0 {
+			got += fmt.Sprintf(" (%d never polled)", r.skipped)
+		}
+		add("noDropped", "0", got, r.unconfirmed+r.skipped == 0)
+	}
+	return cs
+}
+
+// sloTracker accumulates interval failures of each criterion over a run.
+type sloTracker struct {
+	slo       *SLO
+	target    int
+	intervals int
+	failed    map[string]int
+}
+
+func newSLOTracker(slo *SLO, target int) *sloTracker {
+	return &sloTracker{slo: slo, target: target, failed: make(map[string]int)}
+}
+
+// interval checks an interval report, and returns the failed checks.
+func (t *sloTracker) interval(r *Report) []*sloCheck {
+	t.intervals++
+	var failed []*sloCheck
+	for _, c := range t.slo.check(r, r.dur, t.target) {
+		if !c.pass {
+			t.failed[c.name]++
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// result checks the whole run, over which txs were sent for dur. With
+// Intervals set, a criterion which failed in any interval fails too.
+func (t *sloTracker) result(total *Report, dur time.Duration) sloResult {
+	cs := t.slo.check(total, dur, t.target)
+	if t.slo.Intervals {
+		for _, c := range cs {
+			c.intervals = t.intervals
+			c.failed = t.failed[c.name]
+			if c.failed > 0 {
+				c.pass = false
+			}
+		}
+	}
+	return cs
+}
+
+// sloResult is the outcome of every criterion.
+type sloResult []*sloCheck
+
+func (r sloResult) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	for _, c := range r {
+		if err := oe.AddObject(c.name, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// err returns an error naming the failed criteria, if any.
+func (r sloResult) err() error {
+	var failed []string
+	for _, c := range r {
+		if !c.pass {
+			failed = append(failed, c.name)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("SLO violated: %s", strings.Join(failed, ", "))
+}
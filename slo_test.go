@@ -0,0 +1,59 @@
+package chainload
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSLO_check(t *testing.T) {
+	lat := latencySnapshot{{node: 0, method: histSendTx}: newHistogram()}
+	for i := 0; i < 100; i++ {
+		lat[histKey{node: 0, method: histSendTx}].record(time.Duration(i) * time.Millisecond)
+	}
+	r := &Report{dur: 10 * time.Second, txs: 900, errs: 100, unconfirmed: 1, latency: lat}
+	for _, test := range []struct {
+		name string
+		slo  SLO
+		r    *Report
+		exp  map[string]bool // Pass by check.
+	}{
+		{"disabled", SLO{}, r, map[string]bool{}},
+		{"pass", SLO{MinTPS: 0.9, MaxErrorRate: 0.1, MaxP99: 100 * time.Millisecond}, r,
+			map[string]bool{"minTps": true, "maxErrorRate": true, "maxP99": true}},
+		{"fail", SLO{MinTPS: 0.95, MaxErrorRate: 0.05, MaxP99: 50 * time.Millisecond, MaxInclusion: time.Second, NoDropped: true}, r,
+			map[string]bool{"minTps": false, "maxErrorRate": false, "maxP99": false, "maxInclusion": true, "noDropped": false}},
+		{"confirmed", SLO{NoDropped: true}, &Report{dur: time.Second, confirmed: 10},
+			map[string]bool{"noDropped": true}},
+		{"skipped", SLO{NoDropped: true}, &Report{dur: time.Second, confirmed: 10, skipped: 1},
+			map[string]bool{"noDropped": false}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.slo.check(test.r, test.r.dur, 100)
+			if len(got) != len(test.exp) {
+				t.Fatalf("expected %d checks but got %d", len(test.exp), len(got))
+			}
+			for _, c := range got {
+				if exp, ok := test.exp[c.name]; !ok || exp != c.pass {
+					t.Errorf("%s: expected pass %t but got %t (want %s, got %s)", c.name, exp, c.pass, c.want, c.got)
+				}
+			}
+		})
+	}
+}
+
+func TestSLOTracker_result(t *testing.T) {
+	slo := &SLO{MinTPS: 0.5, Intervals: true}
+	tr := newSLOTracker(slo, 100)
+	tr.interval(&Report{dur: time.Second, txs: 100})
+	if failed := tr.interval(&Report{dur: time.Second, txs: 10}); len(failed) != 1 {
+		t.Fatalf("expected 1 failed check but got %d", len(failed))
+	}
+	res := tr.result(&Report{dur: 2 * time.Second, txs: 110}, 2*time.Second)
+	if res.err() == nil {
+		t.Error("expected the failed interval to fail the run")
+	}
+	slo.Intervals = false
+	if err := tr.result(&Report{dur: 2 * time.Second, txs: 110}, 2*time.Second).err(); err != nil {
+		t.Errorf("expected run to pass: %v", err)
+	}
+}
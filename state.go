@@ -26,8 +26,26 @@ var (
 	seederSeedState        state = metrics.GetOrRegisterCounter("state/seeder/seed", nil)
 	seederUpdateNonceState state = metrics.GetOrRegisterCounter("state/seeder/updateNonce", nil)
 
-	senderAssignState    state = metrics.GetOrRegisterCounter("state/sender/assign", nil)
-	senderUpdateGasState state = metrics.GetOrRegisterCounter("state/sender/updateGas", nil)
-	senderSendState      state = metrics.GetOrRegisterCounter("state/sender/send", nil)
-	senderSeedState      state = metrics.GetOrRegisterCounter("state/sender/seed", nil)
+	senderAssignState      state = metrics.GetOrRegisterCounter("state/sender/assign", nil)
+	senderUpdateGasState   state = metrics.GetOrRegisterCounter("state/sender/updateGas", nil)
+	senderSendState        state = metrics.GetOrRegisterCounter("state/sender/send", nil)
+	senderSeedState        state = metrics.GetOrRegisterCounter("state/sender/seed", nil)
+	senderCheckNoncesState state = metrics.GetOrRegisterCounter("state/sender/checkNonces", nil)
 )
+
+// stateCounters lists every state counter, by name, in a fixed order.
+var stateCounters = []struct {
+	name string
+	state
+}{
+	{"seeder/collect", seederCollectState},
+	{"seeder/ensureFunds", seederEnsureFundsState},
+	{"seeder/seed", seederSeedState},
+	{"seeder/updateNonce", seederUpdateNonceState},
+
+	{"sender/assign", senderAssignState},
+	{"sender/updateGas", senderUpdateGasState},
+	{"sender/send", senderSendState},
+	{"sender/seed", senderSeedState},
+	{"sender/checkNonces", senderCheckNoncesState},
+}
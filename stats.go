@@ -1,6 +1,8 @@
 package chainload
 
 import (
+	"math/big"
+	"sync"
 	"time"
 
 	metrics "github.com/rcrowley/go-metrics"
@@ -11,39 +13,157 @@ var (
 	latestBlockNumberTimer = metrics.GetOrRegisterTimer("timer/latestBlockNumber", nil)
 	sendTxTimer            = metrics.GetOrRegisterTimer("timer/sendTx", nil)
 	sendTxErrMeter         = metrics.GetOrRegisterMeter("meter/sendTx/err", nil)
+	sendTxRejectedMeter    = metrics.GetOrRegisterMeter("meter/sendTx/rejected", nil)
 	signTxTimer            = metrics.GetOrRegisterTimer("timer/signTx", nil)
 	suggestGasPriceTimer   = metrics.GetOrRegisterTimer("timer/suggestGasPrice", nil)
 	pendingBalanceAtTimer  = metrics.GetOrRegisterTimer("timer/pendingBalanceAt", nil)
 	pendingNonceAtTimer    = metrics.GetOrRegisterTimer("timer/pendingNonceAt", nil)
+
+	seededFunds bigCounter // Total funds sent from seeders to senders.
 )
 
+// bigCounter is a concurrency safe counter for values which may overflow an int64, like wei.
+type bigCounter struct {
+	mu sync.Mutex
+	v  big.Int
+}
+
+func (c *bigCounter) Add(x *big.Int) {
+	c.mu.Lock()
+	c.v.Add(&c.v, x)
+	c.mu.Unlock()
+}
+
+// Value returns a copy of the current value.
+func (c *bigCounter) Value() *big.Int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return new(big.Int).Set(&c.v)
+}
+
+// addBig returns a new sum of a and b, treating nil as zero.
+func addBig(a, b *big.Int) *big.Int {
+	sum := new(big.Int)
+	if a != nil {
+		sum.Add(sum, a)
+	}
+	if b != nil {
+		sum.Add(sum, b)
+	}
+	return sum
+}
+
 // Report holds statistics for a stretch of time.
 type Report struct {
-	dur  time.Duration // Length of report.
-	txs  int64         // Successful transaction sends.
-	errs int64         // Failed transaction sends.
+	dur    time.Duration // Length of report.
+	txs    int64         // Successful transaction sends.
+	errs   int64         // Failed transaction sends.
+	rej    int64         // Transaction sends rejected by an open circuit breaker, without calling the node.
+	bytes  int64         // Encoded size of successful transaction sends.
+	seeded *big.Int      // Funds sent from seeders to senders.
+
+	confirmed   int64 // Receipts observed, when polling receipts.
+	unconfirmed int64 // Receipts never observed before timing out, when polling receipts.
+	skipped     int64 // Sent txs never polled for receipts, because the pollers were backlogged.
+
+	errClasses map[errClass]int64 // Failed transaction sends by class.
+
+	reads    int64 // Successful read requests.
+	readErrs int64 // Failed read requests.
+
+	wsNotifs     int64 // Subscription notifications received.
+	wsDropped    int64 // Dropped subscription connections.
+	wsReconnects int64
+
+	latency latencySnapshot // Latencies recorded during the report, by node and method.
+
+	spent   costSnapshot    // Cumulative gas spend as of the end of the report.
+	invalid invalidSnapshot // Cumulative invalid tx outcomes as of the end of the report.
 }
 
 func (r *Report) MarshalLogObject(oe zapcore.ObjectEncoder) error {
 	oe.AddDuration("duration", r.dur)
 	oe.AddInt64("txs", r.txs)
 	oe.AddInt64("errs", r.errs)
+	if r.rej > 0 {
+		oe.AddInt64("rejected", r.rej)
+	}
 	oe.AddFloat64("tps", r.TPS())
+	if r.confirmed > 0 {
+		oe.AddInt64("confirmed", r.confirmed)
+	}
+	if r.unconfirmed > 0 {
+		oe.AddInt64("unconfirmed", r.unconfirmed)
+	}
+	if r.skipped > 0 {
+		oe.AddInt64("skipped", r.skipped)
+	}
+	oe.AddInt64("bytes", r.bytes)
+	oe.AddFloat64("bps", float64(r.bytes)/r.dur.Seconds())
+	addBigField(oe, "seeded", r.seeded)
+	if r.reads > 0 || r.readErrs > 0 {
+		oe.AddInt64("reads", r.reads)
+		oe.AddInt64("readErrs", r.readErrs)
+		oe.AddFloat64("rps", float64(r.reads)/r.dur.Seconds())
+	}
+	if r.wsNotifs > 0 || r.wsDropped > 0 || r.wsReconnects > 0 {
+		oe.AddInt64("wsNotifs", r.wsNotifs)
+		oe.AddInt64("wsDropped", r.wsDropped)
+		oe.AddInt64("wsReconnects", r.wsReconnects)
+	}
+	if len(r.errClasses) > 0 {
+		oe.AddObject("errClasses", errClassCounts(r.errClasses))
+	}
+	if len(r.latency) > 0 {
+		oe.AddObject("latency", r.latency.byMethod())
+	}
 	return nil
 }
 
+type errClassCounts map[errClass]int64
+
+func (e errClassCounts) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	for _, class := range errClasses {
+		if n := e[class]; n != 0 {
+			oe.AddInt64(string(class), n)
+		}
+	}
+	return nil
+}
+
+// addErrClasses returns a new map with the sums of a and b.
+func addErrClasses(a, b map[errClass]int64) map[errClass]int64 {
+	sum := make(map[errClass]int64, len(errClasses))
+	for c, n := range a {
+		sum[c] += n
+	}
+	for c, n := range b {
+		sum[c] += n
+	}
+	return sum
+}
+
 func (r *Report) TPS() float64 {
 	return float64(r.txs) / r.dur.Seconds()
 }
 
 type Status struct {
 	latest, recent, total Report
+	spent                 costSnapshot
+	invalid               invalidSnapshot
 }
 
 func (s *Status) MarshalLogObject(oe zapcore.ObjectEncoder) error {
 	oe.AddObject("latest", &s.latest)
 	oe.AddObject("recent", &s.recent)
 	oe.AddObject("total", &s.total)
+	oe.AddObject("spent", &s.spent)
+	if len(s.invalid) > 0 {
+		oe.AddObject("invalid", s.invalid)
+	}
+	if len(s.total.latency) > 0 {
+		oe.AddObject("nodeLatency", s.total.latency.byNode())
+	}
 	return nil
 }
 
@@ -61,24 +181,95 @@ func NewReporter() Reporter {
 
 type reporter struct {
 	// Last report.
-	lastTS   time.Time // Must init with start for seed report to make sense.
-	lastTxs  int64
-	lastErrs int64
+	lastTS     time.Time // Must init with start for seed report to make sense.
+	lastTxs    int64
+	lastConf   int64
+	lastUnconf int64
+	lastSkip   int64
+	lastErrs   int64
+	lastRej    int64
+	lastBytes  int64
+	lastSeeded *big.Int
+	lastClass  map[errClass]int64
+	lastReads  int64
+	lastRErrs  int64
+	lastNotifs int64
+	lastDrops  int64
+	lastRecons int64
+	lastLat    latencySnapshot
 }
 
 func (s *reporter) Report() *Report {
 	now := time.Now()
 	txs := sendTxTimer.Count()
+	conf := receiptsConfirmedMeter.Count()
+	unconf := receiptsUnconfirmedMeter.Count()
+	skip := receiptsSkippedMeter.Count()
 	errs := sendTxErrMeter.Count()
+	rej := sendTxRejectedMeter.Count()
+	bytes := sendTxBytesMeter.Count()
+	seeded := seededFunds.Value()
+	var reads, readErrs int64
+	for _, m := range readMethods {
+		reads += readTimer(m).Count()
+		readErrs += readErrMeter(m).Count()
+	}
+	notifs := wsNewHeadsTimer.Count() + wsLogsTimer.Count() + wsPendingTxsTimer.Count() + wsUnknownTxsMeter.Count()
+	drops := wsDroppedMeter.Count()
+	recons := wsReconnectMeter.Count()
+	lat := latencies.snapshot()
+	classes := make(map[errClass]int64, len(errClasses))
+	for _, c := range errClasses {
+		classes[c] = c.meter().Count()
+	}
 
 	r := &Report{
-		dur:  now.Sub(s.lastTS),
-		txs:  txs - s.lastTxs,
-		errs: errs - s.lastErrs,
+		dur:    now.Sub(s.lastTS),
+		txs:    txs - s.lastTxs,
+		errs:   errs - s.lastErrs,
+		rej:    rej - s.lastRej,
+		bytes:  bytes - s.lastBytes,
+		seeded: new(big.Int).Sub(seeded, addBig(s.lastSeeded, nil)),
+		spent:  spent.snapshot(),
+
+		confirmed:   conf - s.lastConf,
+		unconfirmed: unconf - s.lastUnconf,
+		skipped:     skip - s.lastSkip,
+
+		invalid: newInvalidSnapshot(),
+
+		errClasses: make(map[errClass]int64),
+
+		reads:    reads - s.lastReads,
+		readErrs: readErrs - s.lastRErrs,
+
+		wsNotifs:     notifs - s.lastNotifs,
+		wsDropped:    drops - s.lastDrops,
+		wsReconnects: recons - s.lastRecons,
+
+		latency: lat.sub(s.lastLat),
+	}
+	for c, n := range classes {
+		if d := n - s.lastClass[c]; d != 0 {
+			r.errClasses[c] = d
+		}
 	}
 	s.lastTS = now
 	s.lastTxs = txs
+	s.lastConf = conf
+	s.lastUnconf = unconf
+	s.lastSkip = skip
 	s.lastErrs = errs
+	s.lastRej = rej
+	s.lastBytes = bytes
+	s.lastSeeded = seeded
+	s.lastClass = classes
+	s.lastReads = reads
+	s.lastRErrs = readErrs
+	s.lastNotifs = notifs
+	s.lastDrops = drops
+	s.lastRecons = recons
+	s.lastLat = lat
 
 	return r
 }
@@ -100,11 +291,33 @@ func (r *Reports) Add(rep *Report) *Status {
 
 	r.total.dur += rep.dur
 	r.total.txs += rep.txs
+	r.total.confirmed += rep.confirmed
+	r.total.unconfirmed += rep.unconfirmed
+	r.total.skipped += rep.skipped
 	r.total.errs += rep.errs
+	r.total.rej += rep.rej
+	r.total.bytes += rep.bytes
+	r.total.seeded = addBig(r.total.seeded, rep.seeded)
+	r.total.errClasses = addErrClasses(r.total.errClasses, rep.errClasses)
+	r.total.reads += rep.reads
+	r.total.readErrs += rep.readErrs
+	r.total.wsNotifs += rep.wsNotifs
+	r.total.wsDropped += rep.wsDropped
+	r.total.wsReconnects += rep.wsReconnects
+	r.total.latency = r.total.latency.add(rep.latency)
 
 	return r.status()
 }
 
+// totalReport returns the total of every report, with the latest cumulative
+// snapshots.
+func (r *Reports) totalReport() *Report {
+	t := r.total
+	t.spent = r.latest.spent
+	t.invalid = r.latest.invalid
+	return &t
+}
+
 func (r *Reports) status() *Status {
 	var s Status
 	s.latest = *r.latest
@@ -112,9 +325,24 @@ func (r *Reports) status() *Status {
 		if rec != nil {
 			s.recent.dur += rec.dur
 			s.recent.txs += rec.txs
+			s.recent.confirmed += rec.confirmed
+			s.recent.unconfirmed += rec.unconfirmed
+			s.recent.skipped += rec.skipped
 			s.recent.errs += rec.errs
+			s.recent.rej += rec.rej
+			s.recent.bytes += rec.bytes
+			s.recent.seeded = addBig(s.recent.seeded, rec.seeded)
+			s.recent.errClasses = addErrClasses(s.recent.errClasses, rec.errClasses)
+			s.recent.reads += rec.reads
+			s.recent.readErrs += rec.readErrs
+			s.recent.wsNotifs += rec.wsNotifs
+			s.recent.wsDropped += rec.wsDropped
+			s.recent.wsReconnects += rec.wsReconnects
+			s.recent.latency = s.recent.latency.add(rec.latency)
 		}
 	}
 	s.total = r.total
+	s.spent = r.latest.spent
+	s.invalid = r.latest.invalid
 	return &s
 }
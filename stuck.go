@@ -0,0 +1,75 @@
+package chainload
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/gochain/gochain/v3/core/types"
+	"github.com/gochain/gochain/v3/params"
+	metrics "github.com/rcrowley/go-metrics"
+	"go.uber.org/zap"
+)
+
+var replaceTxMeter = metrics.GetOrRegisterMeter("meter/replaceTx", nil)
+
+// bumpPrice returns price increased by pct percent, plus one to round up.
+func bumpPrice(price *big.Int, pct uint64) *big.Int {
+	b := new(big.Int).Mul(price, new(big.Int).SetUint64(100+pct))
+	b.Div(b, big.NewInt(100))
+	return b.Add(b, big.NewInt(1))
+}
+
+// checkStuck replaces the lowest pending tx with a higher priced no-op if the
+// confirmed nonce has not advanced for stuckAfter, or immediately if force is set.
+func (s *Sender) checkStuck(ctx context.Context, force bool) {
+	t := time.Now()
+	confirmed, err := s.NonceAt(ctx, s.acct.Address, nil)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.lgr.Warn("Failed to get confirmed nonce", zap.Error(err))
+		}
+		return
+	}
+	nonceAtTimer.UpdateSince(t)
+	if s.pending.observe(confirmed) && !force {
+		return
+	}
+	if confirmed >= s.nonce {
+		return // Nothing pending.
+	}
+	if !force && time.Since(s.pending.confirmedAt) < s.stuckAfter {
+		return
+	}
+	s.replace(ctx, confirmed)
+}
+
+// replace re-sends nonce as a self-transfer with a bumped gas price.
+func (s *Sender) replace(ctx context.Context, nonce uint64) {
+	price := s.pending.prices[nonce]
+	if price == nil {
+		price = s.gasPrice
+	}
+	bumped := bumpPrice(price, s.priceBump)
+	if bumped.Cmp(s.gasPrice) == -1 {
+		bumped = new(big.Int).Set(s.gasPrice)
+	}
+	tx := types.NewTransaction(nonce, s.acct.Address, new(big.Int), params.TxGas, bumped, nil)
+	tx, err := s.AccountStore.SignTx(*s.acct, tx)
+	if err != nil {
+		s.lgr.Warn("Failed to sign replacement tx", zap.Error(err))
+		return
+	}
+	// Remember the attempt, so that a rejected replacement is bumped further next time.
+	s.pending.sent(nonce, bumped)
+	if err := s.sendTx(ctx, replaceRole, s.acct.Address, tx); err != nil {
+		if ctx.Err() == nil {
+			s.lgr.Warn("Failed to replace stuck tx", zap.Uint64("nonce", nonce), zapBig("gasPrice", bumped), zap.Error(err))
+		}
+		return
+	}
+	replaceTxMeter.Mark(1)
+	s.pending.confirmedAt = time.Now()
+	s.lgr.Info("Replaced stuck tx", zap.Uint64("nonce", nonce), zapBig("gasPrice", bumped),
+		zap.Stringer("hash", tx.Hash()))
+}
@@ -0,0 +1,64 @@
+package chainload
+
+import (
+	"context"
+	"math/big"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/gochain/gochain/v3/core/types"
+	"github.com/gochain/gochain/v3/params"
+)
+
+func TestSender_checkStuck(t *testing.T) {
+	mock, node, accts, cleanup := newTestNode(t, 1e18)
+	defer cleanup()
+	if err := node.unlock(accts[0]); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	s := &Sender{Node: node, rng: rand.New(rand.NewSource(1)), acct: &accts[0], gasPrice: big.NewInt(1),
+		nonceCheck: time.Millisecond, stuckAfter: 50 * time.Millisecond, priceBump: 10}
+	s.setLgr()
+
+	// Accepted, but never mined.
+	mock.Pause(true)
+	tx, err := node.SignTx(accts[0], types.NewTransaction(0, accts[0].Address, new(big.Int), params.TxGas, big.NewInt(1), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.sendTx(ctx, senderRole, accts[0].Address, tx); err != nil {
+		t.Fatal(err)
+	}
+	s.pending.sent(0, tx.GasPrice())
+	s.nonce = 1
+
+	replaced := replaceTxMeter.Count()
+	// The first check only observes the confirmed nonce.
+	s.checkStuck(ctx, false)
+	if n := replaceTxMeter.Count() - replaced; n != 0 {
+		t.Fatalf("expected no replacement before stuckAfter, but got %d", n)
+	}
+	time.Sleep(s.stuckAfter)
+	s.checkStuck(ctx, false)
+	if n := replaceTxMeter.Count() - replaced; n != 1 {
+		t.Fatalf("expected 1 replacement but got %d", n)
+	}
+	// 1 bumped by 10%, rounded up.
+	if p := mock.Pooled(accts[0].Address, 0); p == nil || p.GasPrice().Int64() != 2 {
+		t.Fatalf("expected pooled replacement with gas price 2 but got %v", p)
+	}
+	// Still stuck, so bumped again.
+	time.Sleep(s.stuckAfter)
+	s.checkStuck(ctx, false)
+	if p := mock.Pooled(accts[0].Address, 0); p == nil || p.GasPrice().Int64() != 3 {
+		t.Fatalf("expected pooled replacement with gas price 3 but got %v", p)
+	}
+
+	mock.Pause(false)
+	mock.Mine()
+	if n := mock.Nonce(accts[0].Address); n != 1 {
+		t.Errorf("expected replacement to be mined, but nonce is %d", n)
+	}
+}
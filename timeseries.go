@@ -0,0 +1,158 @@
+package chainload
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time series formats.
+const (
+	timeseriesCSV   = "csv"
+	timeseriesJSONL = "jsonl"
+)
+
+// timeseries appends a row for every interval report to a file, as CSV or JSON
+// lines. Columns are fixed for the run, so that CSV rows line up.
+type timeseries struct {
+	f    *os.File
+	w    *bufio.Writer
+	csv  *csv.Writer // Nil for JSON lines.
+	rows int
+}
+
+// newTimeseries creates or truncates the file at path. Files ending in .csv are
+// written as CSV, and others as JSON lines.
+func newTimeseries(path string) (*timeseries, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create timeseries file: %v", err)
+	}
+	t := &timeseries{f: f, w: bufio.NewWriter(f)}
+	if timeseriesFormat(path) == timeseriesCSV {
+		t.csv = csv.NewWriter(t.w)
+	}
+	return t, nil
+}
+
+func timeseriesFormat(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return timeseriesCSV
+	}
+	return timeseriesJSONL
+}
+
+// tsCol is a named column value, formatted as a JSON literal.
+type tsCol struct {
+	name string
+	val  string
+}
+
+// write appends a row for iv, and flushes it.
+func (t *timeseries) write(iv *runInterval) error {
+	cols := t.row(iv)
+	if t.csv != nil {
+		if t.rows == 0 {
+			hdr := make([]string, len(cols))
+			for i, c := range cols {
+				hdr[i] = c.name
+			}
+			if err := t.csv.Write(hdr); err != nil {
+				return err
+			}
+		}
+		rec := make([]string, len(cols))
+		for i, c := range cols {
+			rec[i] = c.val
+			if s, err := strconv.Unquote(c.val); err == nil {
+				rec[i] = s
+			}
+		}
+		if err := t.csv.Write(rec); err != nil {
+			return err
+		}
+		t.csv.Flush()
+		if err := t.csv.Error(); err != nil {
+			return err
+		}
+	} else {
+		t.w.WriteByte('{')
+		for i, c := range cols {
+			if i > 0 {
+				t.w.WriteByte(',')
+			}
+			name, _ := json.Marshal(c.name)
+			t.w.Write(name)
+			t.w.WriteByte(':')
+			t.w.WriteString(c.val)
+		}
+		t.w.WriteString("}\n")
+	}
+	t.rows++
+	return t.w.Flush()
+}
+
+// row returns the columns for iv.
+func (t *timeseries) row(iv *runInterval) []tsCol {
+	var cols []tsCol
+	add := func(name, val string) { cols = append(cols, tsCol{name: name, val: val}) }
+	addInt := func(name string, v int64) { add(name, strconv.FormatInt(v, 10)) }
+	addFloat := func(name string, v float64) { add(name, strconv.FormatFloat(v, 'f', -1, 64)) }
+
+	add("time", strconv.Quote(iv.Time.Format(time.RFC3339Nano)))
+	addFloat("elapsed", iv.Elapsed)
+	addFloat("duration", iv.Duration)
+	addInt("targetTps", int64(iv.TargetTPS))
+	addInt("txs", iv.Txs)
+	addFloat("tps", iv.TPS)
+	addInt("confirmed", iv.Confirmed)
+	addFloat("confirmedTps", iv.ConfirmedTPS)
+	addInt("unconfirmed", iv.Unconfirmed)
+	addInt("errs", iv.Errs)
+	addInt("bytes", iv.Bytes)
+	addFloat("bps", iv.BPS)
+	add("seeded", iv.Seeded.String())
+	add("spent", iv.Spent.String())
+	addInt("reads", iv.Reads)
+	addInt("readErrs", iv.ReadErrs)
+	addInt("wsNotifs", iv.WsNotifs)
+	addInt("wsDropped", iv.WsDropped)
+	addInt("wsReconnects", iv.WsReconnects)
+	for _, c := range errClasses {
+		addInt("err/"+string(c), iv.Errors[string(c)])
+	}
+	for _, s := range stateCounters {
+		addInt("state/"+s.name, iv.States[s.name])
+	}
+	for _, m := range histNames {
+		l := iv.Latency[m]
+		addInt(m+"/count", l.Count)
+		addFloat(m+"/p50", l.P50)
+		addFloat(m+"/p90", l.P90)
+		addFloat(m+"/p99", l.P99)
+		addFloat(m+"/p99.9", l.P999)
+		addFloat(m+"/max", l.Max)
+	}
+	for i, n := range iv.Nodes {
+		prefix := "node" + strconv.Itoa(i)
+		addInt(prefix+"/txs", n.Txs)
+		addFloat(prefix+"/tps", n.TPS)
+		addFloat(prefix+"/p50", n.Latency.P50)
+		addFloat(prefix+"/p99", n.Latency.P99)
+	}
+	return cols
+}
+
+func (t *timeseries) Close() error {
+	if err := t.w.Flush(); err != nil {
+		_ = t.f.Close()
+		return err
+	}
+	return t.f.Close()
+}
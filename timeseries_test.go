@@ -0,0 +1,101 @@
+package chainload
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTimeseries_write(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chainload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lat := latencySnapshot{
+		{node: 1, method: histSendTx}: newHistogram(),
+	}
+	lat[histKey{node: 1, method: histSendTx}].record(10 * time.Millisecond)
+	reports := []*Report{
+		{dur: time.Second, txs: 10},
+		{dur: 2 * time.Second, txs: 30, errs: 1, errClasses: map[errClass]int64{errClassNonceTooLow: 1}, latency: lat},
+	}
+
+	for _, name := range []string{"ts.csv", "ts.jsonl"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			ts, err := newTimeseries(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range reports {
+				if err := ts.write(newRunInterval(time.Now(), time.Now(), 20, 2, r)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := ts.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			var rows []map[string]string
+			if timeseriesFormat(path) == timeseriesCSV {
+				recs, err := csv.NewReader(f).ReadAll()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(recs) != len(reports)+1 {
+					t.Fatalf("expected %d rows but got %d", len(reports)+1, len(recs))
+				}
+				for _, rec := range recs[1:] {
+					row := make(map[string]string)
+					for i, name := range recs[0] {
+						row[name] = rec[i]
+					}
+					rows = append(rows, row)
+				}
+			} else {
+				sc := bufio.NewScanner(f)
+				for sc.Scan() {
+					var m map[string]interface{}
+					if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+						t.Fatal(err)
+					}
+					row := make(map[string]string)
+					for k, v := range m {
+						b, _ := json.Marshal(v)
+						row[k] = string(b)
+					}
+					rows = append(rows, row)
+				}
+			}
+			if len(rows) != len(reports) {
+				t.Fatalf("expected %d rows but got %d", len(reports), len(rows))
+			}
+			for col, exp := range map[string]string{
+				"targetTps":                "20",
+				"txs":                      "30",
+				"tps":                      "15",
+				"err/nonceTooLow":          "1",
+				"node1/txs":                "1",
+				"node0/txs":                "0",
+				histSendTx + "/count":      "1",
+				histScheduledSend + "/p99": "0",
+			} {
+				if got := rows[1][col]; got != exp {
+					t.Errorf("%s: expected %s but got %q", col, exp, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package chainload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	dashRefresh = time.Second
+	dashWidth   = 60 // Seconds of TPS history.
+)
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// dashboard periodically redraws a live view of a run to a terminal, in place
+// of the status logs.
+type dashboard struct {
+	w     io.Writer
+	c     *Chainload
+	ready *int64 // Senders assigned an account and funded.
+
+	mu      sync.Mutex
+	seeders []*Seeder // Set once started.
+
+	sent, confirmed []float64 // Per second history, oldest first.
+	errRates        map[errClass]float64
+	recent          latencyByNode // Latencies since the previous sample.
+	secs            float64       // Length of the previous sample.
+
+	lastT       time.Time
+	lastTxs     int64
+	lastConf    int64
+	lastClasses map[errClass]int64
+	lastLat     latencySnapshot
+}
+
+func newDashboard(w io.Writer, c *Chainload, ready *int64) *dashboard {
+	return &dashboard{
+		w:           w,
+		c:           c,
+		ready:       ready,
+		lastT:       time.Now(),
+		lastTxs:     sendTxTimer.Count(),
+		lastConf:    receiptsConfirmedMeter.Count(),
+		errRates:    make(map[errClass]float64),
+		lastClasses: make(map[errClass]int64),
+		lastLat:     latencies.snapshot(),
+	}
+}
+
+// setSeeders sets the seeders to show, once they have started.
+func (d *dashboard) setSeeders(seeders []*Seeder) {
+	d.mu.Lock()
+	d.seeders = seeders
+	d.mu.Unlock()
+}
+
+// run samples and redraws the dashboard until ctx is done, then redraws it
+// once more and calls done.
+func (d *dashboard) run(ctx context.Context, done func()) {
+	defer done()
+	io.WriteString(d.w, "\x1b[?25l\x1b[2J") // Hide the cursor and clear the screen.
+	defer io.WriteString(d.w, "\x1b[?25h")
+	t := time.NewTicker(dashRefresh)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			d.draw()
+			return
+		case <-t.C:
+			d.sample()
+			d.draw()
+		}
+	}
+}
+
+// sample records the change in the global metrics since the last sample.
+func (d *dashboard) sample() {
+	now := time.Now()
+	d.secs = now.Sub(d.lastT).Seconds()
+	txs, conf := sendTxTimer.Count(), receiptsConfirmedMeter.Count()
+	lat := latencies.snapshot()
+	d.recent = lat.sub(d.lastLat).byNode()
+	d.sent = appendHistory(d.sent, float64(txs-d.lastTxs)/d.secs)
+	d.confirmed = appendHistory(d.confirmed, float64(conf-d.lastConf)/d.secs)
+	for _, c := range errClasses {
+		n := c.meter().Count()
+		d.errRates[c] = float64(n-d.lastClasses[c]) / d.secs
+		d.lastClasses[c] = n
+	}
+	d.lastT, d.lastTxs, d.lastConf, d.lastLat = now, txs, conf, lat
+}
+
+// draw renders a frame of the current totals and the latest sample.
+func (d *dashboard) draw() {
+	now := time.Now()
+	var b bytes.Buffer
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, format, args...)
+		b.WriteString("\x1b[K\n") // Clear the rest of the line.
+	}
+	cfg := d.c.config
+	line("chainload  %s  elapsed %s  stage %s  (ctrl-c to stop, logs in %s)", cfg.Version,
+		now.Sub(d.c.start).Truncate(time.Second), d.stage(), cfg.TUILog)
+	line("")
+
+	target := make([]float64, len(d.sent))
+	for i := range target {
+		target[i] = float64(cfg.TPS)
+	}
+	max := float64(cfg.TPS)
+	for _, s := range [][]float64{d.sent, d.confirmed} {
+		for _, v := range s {
+			if v > max {
+				max = v
+			}
+		}
+	}
+	line("%-10s %-*s %8s %8s", fmt.Sprintf("TPS (%ds)", dashWidth), dashWidth, "", "now", "avg")
+	for _, s := range []struct {
+		name string
+		vals []float64
+	}{{"target", target}, {"sent", d.sent}, {"confirmed", d.confirmed}} {
+		line("%-10s %-*s %8.1f %8.1f", s.name, dashWidth, sparkline(s.vals, max), last(s.vals), mean(s.vals))
+	}
+	line("")
+
+	line("%-20s %10s %8s", "Errors", "total", "/s")
+	var anyErrs bool
+	for _, c := range errClasses {
+		n := c.meter().Count()
+		if n == 0 {
+			continue
+		}
+		anyErrs = true
+		line("%-20s %10d %8.1f", c, n, d.errRates[c])
+	}
+	if !anyErrs {
+		line("none")
+	}
+	line("")
+
+	line("%-8s %-9s %8s %10s %10s", "Node", "breaker", "tps", "p99 send", "sent")
+	total := latencies.snapshot().byNode()
+	for _, n := range d.c.nodes {
+		state := "-"
+		if n.breaker != nil {
+			state = n.breaker.State().String()
+		}
+		var tps float64
+		var p99 time.Duration
+		if h := d.recent[n.Number][histSendTx]; h != nil {
+			tps = float64(h.count) / d.secs
+			p99 = h.quantile(0.99)
+		}
+		var sent int64
+		if h := total[n.Number][histSendTx]; h != nil {
+			sent = h.count
+		}
+		line("node%-4d %-9s %8.1f %10s %10d", n.Number, state, tps, p99, sent)
+	}
+	line("")
+
+	line("Senders ready %d/%d", atomic.LoadInt64(d.ready), cfg.Senders)
+	var senders, seeders []string
+	for _, s := range stateCounters {
+		f := fmt.Sprintf("%s %d", s.name[strings.IndexByte(s.name, '/')+1:], s.Count())
+		if strings.HasPrefix(s.name, "sender/") {
+			senders = append(senders, f)
+		} else {
+			seeders = append(seeders, f)
+		}
+	}
+	line("  senders: %s", strings.Join(senders, "  "))
+	line("  seeders: %s", strings.Join(seeders, "  "))
+	line("")
+
+	line("%-8s %-42s %s", "Seeder", "address", "balance (wei)")
+	d.mu.Lock()
+	started := d.seeders
+	d.mu.Unlock()
+	for _, s := range started {
+		bal := "?"
+		if v := s.lastBalance(s.acct.Address); v != nil {
+			bal = v.String()
+		}
+		line("node%-4d %-42s %s", s.Number, s.acct.Address.Hex(), bal)
+	}
+
+	// Home, the frame, then clear anything left below it.
+	io.WriteString(d.w, "\x1b[H"+b.String()+"\x1b[J")
+}
+
+// stage returns the name of the latest stage to begin, or "stopping" once they
+// are all finished.
+func (d *dashboard) stage() string {
+	name := "starting"
+	for _, s := range d.c.stages {
+		s.mu.Lock()
+		begun, ended := !s.start.IsZero(), !s.end.IsZero()
+		s.mu.Unlock()
+		if begun && !ended {
+			name = s.name
+		} else if ended {
+			name = "stopping"
+		}
+	}
+	return name
+}
+
+func appendHistory(h []float64, v float64) []float64 {
+	h = append(h, v)
+	if len(h) > dashWidth {
+		h = h[len(h)-dashWidth:]
+	}
+	return h
+}
+
+// sparkline returns vals as a line of bars scaled to max.
+func sparkline(vals []float64, max float64) string {
+	var b strings.Builder
+	for _, v := range vals {
+		i := 0
+		if max > 0 {
+			i = int(v / max * float64(len(sparks)-1))
+		}
+		if i < 0 {
+			i = 0
+		} else if i >= len(sparks) {
+			i = len(sparks) - 1
+		}
+		b.WriteRune(sparks[i])
+	}
+	return b.String()
+}
+
+func last(vals []float64) float64 {
+	if len(vals) == 0 {
+		return 0
+	}
+	return vals[len(vals)-1]
+}
+
+func mean(vals []float64) float64 {
+	if len(vals) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range vals {
+		sum += v
+	}
+	return sum / float64(len(vals))
+}
@@ -0,0 +1,55 @@
+package chainload
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSparkline(t *testing.T) {
+	for _, test := range []struct {
+		vals []float64
+		max  float64
+		exp  string
+	}{
+		{nil, 10, ""},
+		{[]float64{0, 5, 10}, 10, "▁▄█"},
+		{[]float64{0, 20}, 10, "▁█"},
+		{[]float64{3}, 0, "▁"},
+	} {
+		if got := sparkline(test.vals, test.max); got != test.exp {
+			t.Errorf("%v/%v: expected %q but got %q", test.vals, test.max, test.exp, got)
+		}
+	}
+}
+
+func TestDashboard_draw(t *testing.T) {
+	_, node, accts, cleanup := newTestNode(t, 1e18)
+	defer cleanup()
+	c := &Chainload{config: &Config{Version: "test", TPS: 10, Senders: 2}, nodes: []*Node{node},
+		start: time.Now(), stages: newStages()}
+	ready := int64(1)
+	var buf bytes.Buffer
+	d := newDashboard(&buf, c, &ready)
+	// Drawn before any seeders have started.
+	d.draw()
+	if !strings.Contains(buf.String(), "stage starting") {
+		t.Errorf("expected the starting stage but got:\n%s", buf.String())
+	}
+
+	c.stages[0].begin()
+	d.setSeeders([]*Seeder{{Node: node, acct: &accts[0]}})
+	d.sample()
+	buf.Reset()
+	d.draw()
+	got := buf.String()
+	for _, exp := range []string{"stage " + stageSeeders, "Senders ready 1/2", "node0", accts[0].Address.Hex()} {
+		if !strings.Contains(got, exp) {
+			t.Errorf("expected %q in:\n%s", exp, got)
+		}
+	}
+	if len(d.sent) != 1 || len(d.confirmed) != 1 {
+		t.Errorf("expected 1 sample but got %d sent and %d confirmed", len(d.sent), len(d.confirmed))
+	}
+}
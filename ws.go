@@ -0,0 +1,217 @@
+package chainload
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gochain/gochain/v3/common"
+	"github.com/gochain/gochain/v3/core/types"
+	"github.com/gochain/gochain/v3/rpc"
+	metrics "github.com/rcrowley/go-metrics"
+	"go.uber.org/zap"
+)
+
+// Subscription types.
+const (
+	subNewHeads   = "newHeads"
+	subLogs       = "logs"
+	subPendingTxs = "newPendingTransactions"
+)
+
+var subTypes = []string{subNewHeads, subLogs, subPendingTxs}
+
+var (
+	wsNewHeadsTimer      = metrics.GetOrRegisterTimer("timer/ws/newHeads", nil)       // Since first observed by any connection.
+	wsNewHeadsBlockTimer = metrics.GetOrRegisterTimer("timer/ws/newHeads/block", nil) // Since block timestamp.
+	wsLogsTimer          = metrics.GetOrRegisterTimer("timer/ws/logs", nil)           // Since tx sent.
+	wsPendingTxsTimer    = metrics.GetOrRegisterTimer("timer/ws/newPendingTransactions", nil)
+	wsUnknownTxsMeter    = metrics.GetOrRegisterMeter("meter/ws/newPendingTransactions/unknown", nil)
+	wsDroppedMeter       = metrics.GetOrRegisterMeter("meter/ws/dropped", nil)
+	wsReconnectMeter     = metrics.GetOrRegisterMeter("meter/ws/reconnect", nil)
+	wsConnsCounter       = metrics.GetOrRegisterCounter("counter/ws/conns", nil)
+)
+
+// parseSubTypes parses a csv of subscription types.
+func parseSubTypes(csv string) ([]string, error) {
+	var subs []string
+	for _, sub := range strings.Split(csv, ",") {
+		sub = strings.TrimSpace(sub)
+		if sub == "" {
+			continue
+		}
+		var ok bool
+		for _, st := range subTypes {
+			if sub == st {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown subscription type %q: must be one of %v", sub, subTypes)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+// contractCallData returns calldata for the test contract which encodes t, so
+// that the delay of the resulting log can be measured.
+func contractCallData(t time.Time) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(t.UnixNano()))
+	return b
+}
+
+// blocksSeen records when each recent block was first observed by any subscriber.
+type blocksSeen struct {
+	mu    sync.Mutex
+	first map[common.Hash]time.Time
+	order []common.Hash
+}
+
+var seenBlocks = blocksSeen{first: make(map[common.Hash]time.Time)}
+
+// observe returns the time hash was first observed, recording now if this is the first.
+func (b *blocksSeen) observe(hash common.Hash, now time.Time) time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t, ok := b.first[hash]; ok {
+		return t
+	}
+	b.first[hash] = now
+	b.order = append(b.order, hash)
+	if len(b.order) > 1000 {
+		delete(b.first, b.order[0])
+		b.order = b.order[1:]
+	}
+	return now
+}
+
+// Subscriber holds a WebSocket connection open with a set of subscriptions,
+// reconnecting whenever one is dropped.
+type Subscriber struct {
+	lgr      *zap.Logger
+	URL      string
+	Number   int
+	subs     []string
+	contract *common.Address // Filters logs. Optional.
+	rng      *rand.Rand
+}
+
+func (s *Subscriber) Run(ctx context.Context, done func()) {
+	defer done()
+	for connected := false; ctx.Err() == nil; connected = true {
+		if connected {
+			wsReconnectMeter.Mark(1)
+		}
+		var conn *wsConn
+		bo := backOff{maxWait: 30 * time.Second, wait: 1 * time.Second, lgr: s.lgr, rng: s.rng}
+		if !bo.do(ctx, func() (err error) {
+			conn, err = s.connect(ctx)
+			return
+		}) {
+			return
+		}
+		err := conn.listen(ctx)
+		conn.close()
+		if err != nil {
+			wsDroppedMeter.Mark(1)
+			s.lgr.Warn("Subscription dropped - reconnecting", zap.Error(err))
+		}
+	}
+}
+
+// wsConn is a connection with active subscriptions.
+type wsConn struct {
+	client  *rpc.Client
+	subs    []*rpc.ClientSubscription
+	errs    chan error
+	heads   chan *types.Header
+	logs    chan types.Log
+	pending chan common.Hash
+}
+
+// connect dials s.URL and subscribes to each of s.subs.
+func (s *Subscriber) connect(ctx context.Context) (*wsConn, error) {
+	client, err := rpc.DialWebsocket(ctx, s.URL, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial: %v", err)
+	}
+	c := &wsConn{
+		client:  client,
+		errs:    make(chan error, len(s.subs)),
+		heads:   make(chan *types.Header, 16),
+		logs:    make(chan types.Log, 128),
+		pending: make(chan common.Hash, 1024),
+	}
+	wsConnsCounter.Inc(1)
+	for _, st := range s.subs {
+		var sub *rpc.ClientSubscription
+		switch st {
+		case subNewHeads:
+			sub, err = client.EthSubscribe(ctx, c.heads, subNewHeads)
+		case subLogs:
+			filter := map[string]interface{}{"topics": [][]common.Hash{{testContractTopic}}}
+			if s.contract != nil {
+				filter["address"] = s.contract
+			}
+			sub, err = client.EthSubscribe(ctx, c.logs, subLogs, filter)
+		case subPendingTxs:
+			sub, err = client.EthSubscribe(ctx, c.pending, subPendingTxs)
+		}
+		if err != nil {
+			c.close()
+			return nil, fmt.Errorf("failed to subscribe to %s: %v", st, err)
+		}
+		c.subs = append(c.subs, sub)
+		go func(st string, sub *rpc.ClientSubscription) {
+			// Closed without an error on Unsubscribe.
+			if err := <-sub.Err(); err != nil {
+				c.errs <- fmt.Errorf("%s: %v", st, err)
+			}
+		}(st, sub)
+	}
+	return c, nil
+}
+
+func (c *wsConn) close() {
+	for _, sub := range c.subs {
+		sub.Unsubscribe()
+	}
+	c.client.Close()
+	wsConnsCounter.Dec(1)
+}
+
+// listen measures notification delays until ctx is done (returning nil) or a
+// subscription is dropped.
+func (c *wsConn) listen(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-c.errs:
+			return err
+		case h := <-c.heads:
+			now := time.Now()
+			wsNewHeadsTimer.Update(now.Sub(seenBlocks.observe(h.Hash(), now)))
+			if h.Time != nil {
+				wsNewHeadsBlockTimer.Update(now.Sub(time.Unix(h.Time.Int64(), 0)))
+			}
+		case l := <-c.logs:
+			if len(l.Data) == 8 {
+				wsLogsTimer.UpdateSince(time.Unix(0, int64(binary.BigEndian.Uint64(l.Data))))
+			}
+		case hash := <-c.pending:
+			if sent, ok := recent.sentAt(hash); ok {
+				wsPendingTxsTimer.UpdateSince(sent)
+			} else {
+				wsUnknownTxsMeter.Mark(1)
+			}
+		}
+	}
+}
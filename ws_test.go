@@ -0,0 +1,105 @@
+package chainload
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gochain/gochain/v3/common"
+	"github.com/gochain/gochain/v3/rpc"
+	"go.uber.org/zap"
+)
+
+// MockPendingTxs serves newPendingTransactions subscriptions of the hashes
+// sent to it. It is exported to be registered as an RPC service.
+type MockPendingTxs struct {
+	hashes chan common.Hash
+}
+
+func (s *MockPendingTxs) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	n, ok := rpc.NotifierFromContext(ctx)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	sub := n.CreateSubscription()
+	go func() {
+		for {
+			select {
+			case h := <-s.hashes:
+				_ = n.Notify(sub.ID, h)
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+func TestSubscriber_Run(t *testing.T) {
+	svc := &MockPendingTxs{hashes: make(chan common.Hash)}
+	var mu sync.Mutex
+	var server *rpc.Server
+	// restart closes every connection, and serves new ones from a new server.
+	restart := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if server != nil {
+			server.Stop()
+		}
+		server = rpc.NewServer()
+		if err := server.RegisterName("eth", svc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	restart()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		h := server.WebsocketHandler([]string{"*"})
+		mu.Unlock()
+		h.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	s := &Subscriber{lgr: zap.NewNop(), URL: "ws" + strings.TrimPrefix(srv.URL, "http"),
+		subs: []string{subPendingTxs}, rng: rand.New(rand.NewSource(1))}
+	go s.Run(ctx, wg.Done)
+	defer wg.Wait()
+	defer cancel()
+
+	// notify sends hash until count increments.
+	notify := func(hash common.Hash, count func() int64) {
+		t.Helper()
+		want := count() + 1
+		for deadline := time.Now().Add(10 * time.Second); count() < want; {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for notification of %s", hash.Hex())
+			}
+			select {
+			case svc.hashes <- hash:
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+	recent.add(common.Hash{2}, time.Now())
+	notify(common.Hash{2}, wsPendingTxsTimer.Count)
+	notify(common.Hash{3}, wsUnknownTxsMeter.Count)
+
+	dropped, reconnects := wsDroppedMeter.Count(), wsReconnectMeter.Count()
+	restart()
+	// Notified again once reconnected.
+	notify(common.Hash{2}, wsPendingTxsTimer.Count)
+	if n := wsDroppedMeter.Count() - dropped; n != 1 {
+		t.Errorf("expected 1 dropped connection but got %d", n)
+	}
+	if n := wsReconnectMeter.Count() - reconnects; n != 1 {
+		t.Errorf("expected 1 reconnect but got %d", n)
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/blendle/zapdriver"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 var (
@@ -18,3 +19,12 @@ var (
 func zapBig(key string, b *big.Int) zap.Field {
 	return zap.Reflect(key, json.Number(b.String()))
 }
+
+// addBigField adds b to oe as a proper JSON number, or zero if nil.
+func addBigField(oe zapcore.ObjectEncoder, key string, b *big.Int) {
+	if b == nil {
+		oe.AddInt64(key, 0)
+		return
+	}
+	_ = oe.AddReflected(key, json.Number(b.String()))
+}